@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func runPairings(ctx context.Context, env *env, args []string) error {
+	flags := flag.NewFlagSet("pairings", flag.ContinueOnError)
+	flags.SetOutput(env.out)
+	days := flags.Int("days", 30, "how many days of history to show")
+
+	if err := flags.Parse(args); err != nil {
+		return usageErr("%s", err)
+	}
+
+	since := time.Now().AddDate(0, 0, -*days)
+	pairings, err := store.Pairings(env.db).ListSince(ctx, since)
+	if err != nil {
+		return fmt.Errorf("list pairings: %w", err)
+	}
+
+	total := 0
+	w := tabwriter.NewWriter(env.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tPAIRS")
+	for _, p := range pairings {
+		date := time.Unix(p.Timestamp, 0).UTC().Format(time.DateOnly)
+		fmt.Fprintf(w, "%s\t%d\n", date, p.Value)
+		total += p.Value
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.out, "\n%d pairs over the last %d days\n", total, *days)
+	return nil
+}
+
+func runSecret(ctx context.Context, env *env, args []string) error {
+	if len(args) != 2 || args[0] != "set" {
+		return usageErr("wanted \"set <name>\"")
+	}
+	name := args[1]
+
+	// Read the value from stdin so it doesn't end up in shell history.
+	value, err := bufio.NewReader(env.in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("read secret value: %w", err)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return usageErr("empty secret value")
+	}
+
+	if err := store.Secrets(env.db).Set(ctx, name, value); err != nil {
+		return fmt.Errorf("set secret %q: %w", name, err)
+	}
+
+	fmt.Fprintf(env.out, "Set secret %q in project %s\n", name, env.project)
+	return nil
+}
+
+func runExport(ctx context.Context, env *env, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(env.out)
+	path := flags.String("out", "", "file to write (default stdout)")
+	secrets := flags.Bool("secrets", false, "include API keys and tokens in the archive")
+
+	if err := flags.Parse(args); err != nil {
+		return usageErr("%s", err)
+	}
+
+	out := env.out
+	if *path != "" {
+		f, err := os.Create(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if *secrets {
+		fmt.Fprintln(os.Stderr, "Warning: this archive contains secrets. Store it somewhere safe!")
+	}
+
+	counts, err := store.Archive(env.db).Export(ctx, out, *secrets)
+	if err != nil {
+		return fmt.Errorf("export: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %s from project %s\n", formatCounts(counts), env.project)
+	return nil
+}
+
+func runImport(ctx context.Context, env *env, args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(env.out)
+	path := flags.String("in", "", "file to read (default stdin)")
+
+	if err := flags.Parse(args); err != nil {
+		return usageErr("%s", err)
+	}
+
+	in := env.in
+	if *path != "" {
+		f, err := os.Open(*path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	counts, err := store.Archive(env.db).Import(ctx, in)
+	if err != nil {
+		// Import is idempotent, so it's safe to fix the problem and rerun.
+		return fmt.Errorf("import (after %s): %w", formatCounts(counts), err)
+	}
+
+	fmt.Fprintf(env.out, "Imported %s into project %s\n", formatCounts(counts), env.project)
+	return nil
+}
+
+func runMigrate(ctx context.Context, env *env, args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	flags.SetOutput(env.out)
+	dryRun := flags.Bool("dry-run", false, "only report what would be upgraded")
+
+	if err := flags.Parse(args); err != nil {
+		return usageErr("%s", err)
+	}
+
+	report, err := store.Migrations(env.db).Run(ctx, *dryRun)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+
+	verb := "Upgraded"
+	if *dryRun {
+		verb = "Would upgrade"
+	}
+	fmt.Fprintf(env.out, "Scanned %s in project %s\n", formatCounts(report.Scanned), env.project)
+	fmt.Fprintf(env.out, "%s %s\n", verb, formatCounts(report.Outdated))
+
+	if len(report.Failures) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(env.out, "\n%d documents could not be decoded:\n", len(report.Failures))
+	w := tabwriter.NewWriter(env.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tERROR")
+	for _, f := range report.Failures {
+		fmt.Fprintf(w, "%s\t%s\n", f.Path, f.Error)
+	}
+	return w.Flush()
+}
+
+// formatCounts describes the number of documents in each collection.
+func formatCounts(counts map[string]int) string {
+	var parts []string
+	for collection, n := range counts {
+		parts = append(parts, fmt.Sprintf("%d %s", n, collection))
+	}
+	if len(parts) == 0 {
+		return "nothing"
+	}
+	sort.Strings(parts)
+	return strings.Join(parts, ", ")
+}
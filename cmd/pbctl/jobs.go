@@ -0,0 +1,359 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/jobauth"
+	"github.com/recursecenter/pairing-bot/internal/selection"
+	"github.com/recursecenter/pairing-bot/recurse"
+	"github.com/recursecenter/pairing-bot/store"
+	"github.com/recursecenter/pairing-bot/zulip"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// dryRuns preview what each job would do, without changing anything.
+var dryRuns = map[string]func(ctx context.Context, env *env) error{
+	"match":           dryRunMatch,
+	"endofbatch":      dryRunEndOfBatch,
+	"offboardwarning": dryRunOffboardWarning,
+	"welcome":         dryRunWelcome,
+	"checkin":         dryRunCheckin,
+	"sync":            dryRunSync,
+	"onboard":         dryRunOnboard,
+	"outbox":          dryRunOutbox,
+}
+
+func runJob(ctx context.Context, env *env, args []string) error {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(env.out)
+	dryRun := flags.Bool("dry-run", false, "show what the job would do without running it")
+	baseURL := flags.String("url", "", "base URL of the Pairing Bot server (default https://<project>.appspot.com)")
+	operator := flags.String("operator", os.Getenv("USER"), "your name, for the server's audit log")
+	force := flags.Bool("force", false, "run the job even if it already ran this period")
+
+	if err := flags.Parse(args); err != nil {
+		return usageErr("%s", err)
+	}
+	if flags.NArg() != 1 {
+		return usageErr("wanted exactly one job name")
+	}
+
+	job := flags.Arg(0)
+	preview, ok := dryRuns[job]
+	if !ok {
+		return usageErr("unknown job %q", job)
+	}
+
+	if *dryRun {
+		return preview(ctx, env)
+	}
+
+	if *operator == "" {
+		return usageErr("--operator is required")
+	}
+	if *baseURL == "" {
+		*baseURL = fmt.Sprintf("https://%s.appspot.com", env.project)
+	}
+
+	return triggerJob(ctx, env, *baseURL, job, *operator, *force)
+}
+
+// triggerJob asks the server to run the job with a request signed by the
+// shared trigger secret. Unless force is set, the server skips (and reports)
+// a job that already ran this period.
+func triggerJob(ctx context.Context, env *env, baseURL, job, operator string, force bool) error {
+	secret, err := store.Secrets(env.db).Get(ctx, "job_trigger_secret")
+	if err != nil {
+		return fmt.Errorf("get job trigger secret: %w", err)
+	}
+
+	url := strings.TrimSuffix(baseURL, "/") + "/" + job
+	if force {
+		url += "?force=true"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return err
+	}
+	jobauth.Sign(req, secret, operator, time.Now())
+
+	// Jobs can take a while, but not forever.
+	client := &http.Client{Timeout: 10 * time.Minute}
+
+	fmt.Fprintf(env.out, "Running %s on %s...\n", job, baseURL)
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("trigger %s: %w", job, err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("trigger %s: %s %s", job, resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	fmt.Fprintf(env.out, "Done: %s\n", resp.Status)
+	return nil
+}
+
+// dryRunMatch makes one possible set of today's matches, the same way the
+// match job does. The job shuffles everyone with a fresh random seed, so the
+// real pairs will be different, but who's left out and how many pairs are made
+// usually won't be.
+func dryRunMatch(ctx context.Context, env *env) error {
+	recursers, err := store.Recursers(env.db).ListPairingTomorrow(ctx)
+	if err != nil {
+		return fmt.Errorf("list today's recursers: %w", err)
+	}
+
+	fmt.Fprintf(env.out, "%d recursers would be matched today (%s).\n", len(recursers), time.Now().UTC().Weekday())
+
+	members, err := env.zulip.ListUsers(ctx)
+	if err != nil {
+		// The job keeps everyone when Zulip can't say who's active.
+		fmt.Fprintf(env.out, "Could not check for deactivated Zulip accounts, so nobody would be left out: %s\n", err)
+	} else {
+		accounts := make(map[int64]zulip.Account, len(members))
+		for _, a := range members {
+			accounts[a.UserID] = a
+		}
+
+		var invalid []store.Recurser
+		recursers, invalid = selection.Messageable(recursers, accounts)
+		for _, r := range invalid {
+			fmt.Fprintf(env.out, "  Would flag %s (%d) as deactivated and leave them out\n", r.Name, r.ID)
+		}
+	}
+
+	seed := rand.Int63()
+	selection.Shuffle(recursers, seed)
+	pairs, unmatched := selection.PairUp(recursers)
+
+	fmt.Fprintf(env.out, "With random seed %d, would match:\n", seed)
+	for _, pair := range pairs {
+		fmt.Fprintf(env.out, "  %s (%d) with %s (%d)\n", pair[0].Name, pair[0].ID, pair[1].Name, pair[1].ID)
+	}
+	for _, r := range unmatched {
+		fmt.Fprintf(env.out, "  Nobody for %s (%d) partners=%s\n", r.Name, r.ID, cmp.Or(r.Partners, store.PartnersAnyone))
+	}
+
+	fmt.Fprintf(env.out, "%d pairs would be made, and %d recursers left unmatched.\n", len(pairs), len(unmatched))
+	return nil
+}
+
+func dryRunEndOfBatch(ctx context.Context, env *env) error {
+	recursers, err := store.Recursers(env.db).GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list subscribers: %w", err)
+	}
+
+	profiles, err := env.recurse.ActiveRecursers(ctx)
+	if err != nil {
+		return fmt.Errorf("get active Recursers: %w", err)
+	}
+
+	offboard, keep := selection.Leavers(recursers, selection.AtRC(profiles))
+	for _, r := range keep {
+		fmt.Fprintf(env.out, "  Would keep %s (%d) as an alum\n", r.Name, r.ID)
+	}
+	for _, r := range offboard {
+		fmt.Fprintf(env.out, "  Would offboard %s (%d)\n", r.Name, r.ID)
+	}
+
+	fmt.Fprintf(env.out, "%d of %d subscribers would be offboarded.\n", len(offboard), len(recursers))
+	return nil
+}
+
+func dryRunOffboardWarning(ctx context.Context, env *env) error {
+	batches, err := env.recurse.AllBatches(ctx)
+	if err != nil {
+		return fmt.Errorf("get list of batches: %w", err)
+	}
+
+	recursers, err := store.Recursers(env.db).GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list subscribers: %w", err)
+	}
+
+	ending := selection.EndingBatches(batches, time.Now())
+	for _, batch := range ending {
+		profiles, err := env.recurse.BatchRecursers(ctx, batch.ID)
+		if err != nil {
+			return fmt.Errorf("get recursers in %s: %w", batch.Name, err)
+		}
+
+		warn, staying := selection.OffboardWarnings(batch, profiles, recursers)
+		fmt.Fprintf(env.out, "%s ends %s. Would warn (unless already warned):\n", batch.Name, time.Time(batch.EndDate).Format(time.DateOnly))
+		for _, r := range warn {
+			fmt.Fprintf(env.out, "  %s (%d)\n", r.Name, r.ID)
+		}
+		for _, r := range staying {
+			fmt.Fprintf(env.out, "  (not %s (%d), who is staying on)\n", r.Name, r.ID)
+		}
+	}
+
+	if len(ending) == 0 {
+		fmt.Fprintln(env.out, "No batches end soon, so nobody would be warned.")
+	}
+	return nil
+}
+
+func dryRunWelcome(ctx context.Context, env *env) error {
+	batches, err := env.recurse.AllBatches(ctx)
+	if err != nil {
+		return fmt.Errorf("get list of batches: %w", err)
+	}
+
+	welcomes := selection.Welcomes(batches, time.Now())
+	for _, w := range welcomes {
+		start := time.Time(w.Batch.StartDate).Format(time.DateOnly)
+		if w.Mini {
+			fmt.Fprintf(env.out, "Would post the mini batch welcome for %s (started %s), unless it was already welcomed.\n", w.Batch.Name, start)
+		} else {
+			fmt.Fprintf(env.out, "Would welcome %s (started %s), unless it was already welcomed.\n", w.Batch.Name, start)
+		}
+	}
+
+	if len(welcomes) == 0 {
+		fmt.Fprintln(env.out, "Would not post: no full batch is in its second week, and no mini batch is in progress.")
+	}
+	return nil
+}
+
+func dryRunCheckin(ctx context.Context, env *env) error {
+	pairings, err := store.Pairings(env.db).GetTotalsDuringLastWeek(ctx)
+	if err != nil {
+		return fmt.Errorf("count last week's pairings: %w", err)
+	}
+
+	recursers, err := store.Recursers(env.db).GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list subscribers: %w", err)
+	}
+
+	alumni := 0
+	for _, r := range recursers {
+		if r.IsAlum() {
+			alumni++
+		}
+	}
+
+	fmt.Fprintf(env.out, "Would post a checkin with %d subscribers (%d alumni) and %d pairings in the last week (%d with alumni).\n", len(recursers), alumni, pairings.Value, pairings.Alumni)
+	return nil
+}
+
+func dryRunSync(ctx context.Context, env *env) error {
+	recursers, err := store.Recursers(env.db).GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list subscribers: %w", err)
+	}
+
+	members, err := env.zulip.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list Zulip users: %w", err)
+	}
+
+	profiles, err := env.recurse.ActiveRecursers(ctx)
+	if err != nil {
+		return fmt.Errorf("get active Recursers: %w", err)
+	}
+
+	now := time.Now()
+	changed := 0
+	for _, r := range recursers {
+		i := slices.IndexFunc(members, func(a zulip.Account) bool { return a.UserID == r.ID })
+		var account zulip.Account
+		if i >= 0 {
+			account = members[i]
+		}
+
+		j := slices.IndexFunc(profiles, func(p recurse.Profile) bool { return p.ZulipID == r.ID })
+		var profile recurse.Profile
+		if j >= 0 {
+			profile = profiles[j]
+		}
+
+		fields := selection.Synced(r, account, i >= 0, profile, j >= 0, now)
+		if fields == r.Synced() {
+			continue
+		}
+		changed++
+		fmt.Fprintf(env.out, "  Would update %s (%d): %+v -> %+v\n", r.Name, r.ID, r.Synced(), fields)
+	}
+
+	fmt.Fprintf(env.out, "%d of %d subscribers would be updated.\n", changed, len(recursers))
+	return nil
+}
+
+func dryRunOnboard(ctx context.Context, env *env) error {
+	profiles, err := env.recurse.ActiveRecursers(ctx)
+	if err != nil {
+		return fmt.Errorf("get active Recursers: %w", err)
+	}
+
+	now := time.Now()
+	count := 0
+	for _, p := range profiles {
+		stint, ok := selection.Newcomer(p, now)
+		if !ok {
+			continue
+		}
+
+		_, err := store.Recursers(env.db).Get(ctx, p.ZulipID)
+		if err == nil {
+			continue
+		} else if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("look up %s (%d): %w", p.Name, p.ZulipID, err)
+		}
+
+		progress, err := store.Onboarding(env.db).Get(ctx, p.ZulipID)
+		if err != nil {
+			return fmt.Errorf("get onboarding progress for %s (%d): %w", p.Name, p.ZulipID, err)
+		}
+		if progress != nil {
+			continue
+		}
+
+		count++
+		fmt.Fprintf(env.out, "  Would introduce %s (%d), new in %s\n", p.Name, p.ZulipID, stint.Batch.Name)
+	}
+
+	fmt.Fprintf(env.out, "%d new Recursers would be introduced to Pairing Bot.\n", count)
+	return nil
+}
+
+func dryRunOutbox(ctx context.Context, env *env) error {
+	pending, err := store.Outbox(env.db).ListPending(ctx)
+	if err != nil {
+		return fmt.Errorf("list pending outbox messages: %w", err)
+	}
+
+	now := time.Now()
+	for _, msg := range pending {
+		status := "would retry"
+		if msg.Expired(now) {
+			status = "expired"
+		}
+		fmt.Fprintf(env.out, "  %s %s to %v (%d attempts): %s\n", msg.Source, status, msg.Recipients, msg.Attempts, msg.LastError)
+	}
+
+	fmt.Fprintf(env.out, "%d messages are waiting to be delivered.\n", len(pending))
+
+	prunable, err := store.Outbox(env.db).ListPrunable(ctx, now.Add(-store.OutboxRetention))
+	if err != nil {
+		return fmt.Errorf("list old outbox messages: %w", err)
+	}
+	fmt.Fprintf(env.out, "%d delivered or failed messages are old enough to be pruned.\n", len(prunable))
+	return nil
+}
@@ -0,0 +1,239 @@
+// Command pbctl is a command-line tool for operating Pairing Bot.
+//
+// It talks directly to Pairing Bot's Firestore database (production, dev, or a
+// local emulator) and reuses the same API clients as the bot itself.
+//
+// Usage:
+//
+//	pbctl [flags] <command> [arguments]
+//
+// Run "pbctl help" for the list of commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"github.com/recursecenter/pairing-bot/recurse"
+	"github.com/recursecenter/pairing-bot/store"
+	"github.com/recursecenter/pairing-bot/zulip"
+)
+
+// ErrUsage is returned when a command's arguments are invalid.
+var ErrUsage = errors.New("usage error")
+
+// A command is one pbctl subcommand.
+type command struct {
+	usage string
+	help  string
+	run   func(ctx context.Context, env *env, args []string) error
+}
+
+var commands = map[string]command{
+	"subscribers": {
+		usage: "subscribers",
+		help:  "List everyone subscribed to Pairing Bot",
+		run:   runSubscribers,
+	},
+	"recurser": {
+		usage: "recurser get <zulip-id> | recurser set <zulip-id> [--schedule=mon,wed] [--skip=true|false] [--at-rc=true|false]",
+		help:  "Inspect or edit one subscriber's record",
+		run:   runRecurser,
+	},
+	"run": {
+		usage: "run [--dry-run] [--force] [--url=URL] [--operator=NAME] <match|endofbatch|offboardwarning|welcome|checkin|sync|onboard|outbox>",
+		help:  "Trigger a job on the server, or preview what it would do with --dry-run",
+		run:   runJob,
+	},
+	"pairings": {
+		usage: "pairings [--days=N]",
+		help:  "Show the daily pairing counts",
+		run:   runPairings,
+	},
+	"secret": {
+		usage: "secret set <name> (reads the value from stdin)",
+		help:  "Set one of the bot's secrets",
+		run:   runSecret,
+	},
+	"export": {
+		usage: "export [--out=FILE] [--secrets]",
+		help:  "Back up all of the bot's data as a JSON Lines archive",
+		run:   runExport,
+	},
+	"import": {
+		usage: "import [--in=FILE]",
+		help:  "Restore an archive written by export (safe to repeat)",
+		run:   runImport,
+	},
+	"migrate": {
+		usage: "migrate [--dry-run]",
+		help:  "Upgrade stored documents to the current schema and list any that can't be read",
+		run:   runMigrate,
+	},
+}
+
+// env holds the clients and configuration shared by all commands.
+type env struct {
+	db      *firestore.Client
+	recurse *recurse.Client
+	zulip   *zulip.Client
+
+	project string
+	in      io.Reader
+	out     io.Writer
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("pbctl: ")
+
+	flags := flag.NewFlagSet("pbctl", flag.ExitOnError)
+	prod := flags.Bool("prod", false, "operate on the production project instead of dev")
+	project := flags.String("project", "", "Google Cloud project ID (overrides --prod)")
+	emulator := flags.String("emulator", "", "Firestore emulator host:port (defaults to $FIRESTORE_EMULATOR_HOST)")
+	flags.Usage = func() { usage(flags) }
+
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		log.Fatal(err)
+	}
+
+	args := flags.Args()
+	if len(args) == 0 || args[0] == "help" {
+		usage(flags)
+		return
+	}
+
+	cmd, ok := commands[args[0]]
+	if !ok {
+		log.Printf("unknown command %q", args[0])
+		usage(flags)
+		os.Exit(2)
+	}
+
+	// These match the values in the bot's own main.go.
+	projectID := "pairing-bot-dev"
+	botUsername := "dev-pairing-bot@recurse.zulipchat.com"
+	if *prod {
+		projectID = "pairing-bot-284823"
+		botUsername = "pairing-bot@recurse.zulipchat.com"
+	}
+	if *project != "" {
+		projectID = *project
+	}
+
+	// The Firestore client connects to the emulator whenever this is set.
+	if *emulator != "" {
+		os.Setenv("FIRESTORE_EMULATOR_HOST", *emulator)
+	}
+
+	ctx := context.Background()
+
+	e, err := newEnv(ctx, projectID, botUsername)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer e.db.Close()
+
+	if err := cmd.run(ctx, e, args[1:]); err != nil {
+		if errors.Is(err, ErrUsage) {
+			log.Printf("%s\nusage: pbctl %s", err, cmd.usage)
+			os.Exit(2)
+		}
+		log.Fatal(err)
+	}
+}
+
+// newEnv connects to the project's database and sets up API clients that
+// read their credentials from it, the same way the bot does.
+func newEnv(ctx context.Context, projectID, botUsername string) (*env, error) {
+	db, err := firestore.NewClient(ctx, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("connect to Firestore: %w", err)
+	}
+
+	zulipCredentials := func(ctx context.Context) (zulip.Credentials, error) {
+		password, err := store.Secrets(db).Get(ctx, "zulip_api_key")
+		if err != nil {
+			return zulip.Credentials{}, err
+		}
+
+		return zulip.Credentials{
+			Username: botUsername,
+			Password: password,
+		}, nil
+	}
+
+	zulipClient, err := zulip.NewClient(zulipCredentials)
+	if err != nil {
+		return nil, err
+	}
+
+	recurseAccessToken := func(ctx context.Context) (recurse.AccessToken, error) {
+		token, err := store.Secrets(db).Get(ctx, "recurse_access_token")
+		if err != nil {
+			return "", err
+		}
+		return recurse.AccessToken(token), nil
+	}
+
+	recurseClient, err := recurse.NewClient(recurseAccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	return &env{
+		db:      db,
+		recurse: recurseClient,
+		zulip:   zulipClient,
+
+		project: projectID,
+		in:      os.Stdin,
+		out:     os.Stdout,
+	}, nil
+}
+
+func usage(flags *flag.FlagSet) {
+	out := flags.Output()
+	fmt.Fprintf(out, "Usage: pbctl [flags] <command> [arguments]\n\nCommands:\n")
+
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		cmd := commands[name]
+		fmt.Fprintf(out, "  %-12s %s\n  %-12s   pbctl %s\n", name, cmd.help, "", cmd.usage)
+	}
+
+	fmt.Fprintf(out, "\nFlags:\n")
+	flags.PrintDefaults()
+}
+
+// usageErr formats an ErrUsage with details.
+func usageErr(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrUsage, fmt.Sprintf(format, args...))
+}
+
+// formatSchedule lists the scheduled days in week order.
+func formatSchedule(schedule map[string]bool) string {
+	var days []string
+	for _, day := range []string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"} {
+		if schedule[day] {
+			days = append(days, day[:3])
+		}
+	}
+	if len(days) == 0 {
+		return "-"
+	}
+	return strings.Join(days, ",")
+}
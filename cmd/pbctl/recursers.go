@@ -0,0 +1,178 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func runSubscribers(ctx context.Context, env *env, args []string) error {
+	if len(args) != 0 {
+		return usageErr("unexpected arguments %q", args)
+	}
+
+	recursers, err := store.Recursers(env.db).GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list subscribers: %w", err)
+	}
+
+	w := tabwriter.NewWriter(env.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tNAME\tEMAIL\tSCHEDULE\tSKIPPING\tAT RC\tALUMNI\tPARTNERS")
+	for _, r := range recursers {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%t\t%t\t%t\t%s\n", r.ID, r.Name, r.Email, formatSchedule(r.Schedule), r.IsSkippingTomorrow, r.CurrentlyAtRC, r.Alumni, cmp.Or(r.Partners, store.PartnersAnyone))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(env.out, "\n%d subscribers\n", len(recursers))
+	return nil
+}
+
+func runRecurser(ctx context.Context, env *env, args []string) error {
+	if len(args) < 2 {
+		return usageErr("wanted a subcommand and Zulip ID")
+	}
+
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return usageErr("invalid Zulip ID %q", args[1])
+	}
+
+	switch args[0] {
+	case "get":
+		if len(args) != 2 {
+			return usageErr("unexpected arguments %q", args[2:])
+		}
+		return getRecurser(ctx, env, id)
+
+	case "set":
+		return setRecurser(ctx, env, id, args[2:])
+
+	default:
+		return usageErr("unknown subcommand %q", args[0])
+	}
+}
+
+// getRecurser prints the stored record along with the user's Zulip account
+// status, which the bot relies on for sending messages.
+func getRecurser(ctx context.Context, env *env, id int64) error {
+	rec, err := store.Recursers(env.db).Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get recurser %d: %w", id, err)
+	}
+
+	enc := json.NewEncoder(env.out)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(rec); err != nil {
+		return err
+	}
+
+	account, err := env.zulip.GetUser(ctx, id)
+	if err != nil {
+		fmt.Fprintf(env.out, "\nCould not look up Zulip account: %s\n", err)
+		return nil
+	}
+
+	fmt.Fprintf(env.out, "\nZulip account: %s <%s> active=%t bot=%t\n", account.FullName, account.Email, account.IsActive, account.IsBot)
+	return nil
+}
+
+// setRecurser edits the fields named by the flags and leaves the rest alone.
+func setRecurser(ctx context.Context, env *env, id int64, args []string) error {
+	flags := flag.NewFlagSet("recurser set", flag.ContinueOnError)
+	flags.SetOutput(env.out)
+	schedule := flags.String("schedule", "", "comma-separated days to pair on, e.g., mon,wed,fri")
+	skip := flags.String("skip", "", "whether to skip pairing tomorrow (true or false)")
+	atRC := flags.String("at-rc", "", "whether the recurser is currently at RC (true or false)")
+
+	if err := flags.Parse(args); err != nil {
+		return usageErr("%s", err)
+	}
+	if flags.NArg() != 0 {
+		return usageErr("unexpected arguments %q", flags.Args())
+	}
+
+	recursers := store.Recursers(env.db)
+
+	// Each flag only updates its own field, so that nothing else the
+	// recurser changes at the same time is overwritten. The flags are all
+	// checked before anything is updated.
+	var updates []func() error
+
+	if *schedule != "" {
+		days, err := parseDays(*schedule)
+		if err != nil {
+			return usageErr("%s", err)
+		}
+		updates = append(updates, func() error {
+			return recursers.SetSchedule(ctx, id, store.NewSchedule(days))
+		})
+	}
+
+	if *skip != "" {
+		skipping, err := strconv.ParseBool(*skip)
+		if err != nil {
+			return usageErr("invalid --skip value %q", *skip)
+		}
+		updates = append(updates, func() error {
+			return recursers.SetSkippingTomorrow(ctx, id, skipping)
+		})
+	}
+
+	if *atRC != "" {
+		currentlyAtRC, err := strconv.ParseBool(*atRC)
+		if err != nil {
+			return usageErr("invalid --at-rc value %q", *atRC)
+		}
+		updates = append(updates, func() error {
+			return recursers.SetCurrentlyAtRC(ctx, id, currentlyAtRC)
+		})
+	}
+
+	for _, update := range updates {
+		if err := update(); err != nil {
+			return fmt.Errorf("update recurser %d: %w", id, err)
+		}
+	}
+
+	rec, err := recursers.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("get recurser %d: %w", id, err)
+	}
+
+	fmt.Fprintf(env.out, "Updated %s (%d): schedule=%s skipping=%t at-rc=%t\n", rec.Name, rec.ID, formatSchedule(rec.Schedule), rec.IsSkippingTomorrow, rec.CurrentlyAtRC)
+	return nil
+}
+
+// parseDays expands a comma-separated list of day names or their first three
+// letters into canonical schedule keys.
+func parseDays(list string) ([]string, error) {
+	var days []string
+
+	for _, word := range strings.Split(list, ",") {
+		word = strings.ToLower(strings.TrimSpace(word))
+
+		found := false
+		for day := range store.EmptySchedule() {
+			if len(word) >= 3 && strings.HasPrefix(day, word) {
+				days = append(days, day)
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("unknown day %q", word)
+		}
+	}
+
+	return days, nil
+}
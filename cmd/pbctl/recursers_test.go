@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+)
+
+func Test_parseDays(t *testing.T) {
+	days, err := parseDays("mon,Wed, friday")
+	assert.NoError(t, err)
+	assert.Equal(t, days, []string{"monday", "wednesday", "friday"})
+
+	for _, bad := range []string{"", "mo", "someday", "mon,,fri"} {
+		t.Run(bad, func(t *testing.T) {
+			_, err := parseDays(bad)
+			if err == nil {
+				t.Errorf("expected error for %q", bad)
+			}
+		})
+	}
+}
+
+func Test_formatSchedule(t *testing.T) {
+	assert.Equal(t, formatSchedule(map[string]bool{"friday": true, "monday": true, "sunday": false}), "mon,fri")
+	assert.Equal(t, formatSchedule(map[string]bool{}), "-")
+}
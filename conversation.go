@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+// conversationTimeout is how long the bot waits for an answer to a question
+// before giving up on it.
+const conversationTimeout = 15 * time.Minute
+
+// ask records that the user has been asked a question on behalf of the flow's
+// command, so that their next message can be read as the answer. It returns
+// the question to reply with.
+func (pl *PairingLogic) ask(ctx context.Context, rec *store.Recurser, flow string, question string) (string, error) {
+	if err := store.Conversations(pl.db).Ask(ctx, rec.ID, flow, conversationTimeout); err != nil {
+		return writeErrorMessage, err
+	}
+	return question, nil
+}
+
+// answer reads the message as an answer to the question waiting on the user,
+// if there is one, and returns the command that the answer stands for. It
+// returns false if the message should be parsed as a command instead.
+//
+// The user only gets one chance to answer: any message clears the question,
+// so that ignoring it and moving on to something else abandons it.
+func (pl *PairingLogic) answer(ctx context.Context, rec *store.Recurser, message string) (string, []string, bool) {
+	conversations := store.Conversations(pl.db)
+
+	conv, err := conversations.Get(ctx, rec.ID)
+	if err != nil {
+		log.Printf("Could not read the conversation with %s (ID %d): %s", rec.Name, rec.ID, err)
+		return "", nil, false
+	}
+	if conv == nil {
+		return "", nil, false
+	}
+
+	if err := conversations.Clear(ctx, rec.ID); err != nil {
+		log.Printf("Could not clear the conversation with %s (ID %d): %s", rec.Name, rec.ID, err)
+	}
+
+	cmd, args, ok := parseAnswer(conv.Flow, message)
+	if !ok {
+		return "", nil, false
+	}
+
+	// Don't act on an answer to a question the user has probably forgotten
+	// about, but don't pretend not to understand it either.
+	if conv.Expired(time.Now()) {
+		return "expired", []string{conv.Flow}, true
+	}
+	return cmd, args, true
+}
+
+// parseAnswer interprets the message as an answer to a question asked on
+// behalf of the flow's command. Answers stand for the command that finishes
+// the flow, or "cancel" to drop it.
+func parseAnswer(flow string, message string) (string, []string, bool) {
+	words := strings.Fields(strings.ToLower(message))
+	if len(words) == 0 {
+		return "", nil, false
+	}
+
+	if len(words) == 1 && words[0] == "cancel" {
+		return "cancel", []string{flow}, true
+	}
+
+	switch flow {
+	case "schedule":
+		var days []string
+		for _, word := range words {
+			day, err := parseDay(word)
+			if err != nil {
+				return "", nil, false
+			}
+			days = append(days, day)
+		}
+		return "schedule", days, true
+
+	case "unsubscribe", "forget-me":
+		yes, ok := parseYesNo(words)
+		if !ok {
+			return "", nil, false
+		}
+		if yes {
+			return flow, []string{"confirm"}, true
+		}
+		return "cancel", []string{flow}, true
+
+	case "subscribe":
+		// Either way, they still want to subscribe.
+		yes, ok := parseYesNo(words)
+		if !ok {
+			return "", nil, false
+		}
+		if yes {
+			return "subscribe", []string{"restore"}, true
+		}
+		return "subscribe", []string{"fresh"}, true
+	}
+
+	return "", nil, false
+}
+
+// parseYesNo reads the words as a one-word yes or no. It returns false if
+// they're neither.
+func parseYesNo(words []string) (yes bool, ok bool) {
+	if len(words) != 1 {
+		return false, false
+	}
+	switch words[0] {
+	case "yes", "y", "yep", "yeah":
+		return true, true
+	case "no", "n", "nope":
+		return false, true
+	}
+	return false, false
+}
+
+// cancelMessage confirms that the flow was dropped when the user declined to
+// answer its question.
+func cancelMessage(flow string) string {
+	switch flow {
+	case "unsubscribe":
+		return "Okay, you're still subscribed!"
+	case "forget-me":
+		return "Okay, I haven't deleted anything."
+	case "subscribe":
+		return "Okay, you're still unsubscribed!"
+	default:
+		return "Okay, never mind!"
+	}
+}
+
+// expiredMessage tells the user that their answer to the flow's question came
+// too late.
+func expiredMessage(flow string) string {
+	return fmt.Sprintf("Sorry, I stopped waiting for an answer to that after %d minutes. Send `%s` again if you'd still like to.", int(conversationTimeout.Minutes()), flow)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+)
+
+func Test_parseAnswer(t *testing.T) {
+	accepted := map[string]struct {
+		Flow, Message string
+		Cmd           string
+		Args          []string
+	}{
+		"days":             {"schedule", "Mon wed  FRI", "schedule", []string{"monday", "wednesday", "friday"}},
+		"confirm":          {"unsubscribe", "yes", "unsubscribe", []string{"confirm"}},
+		"confirm shortly":  {"forget-me", " Y ", "forget-me", []string{"confirm"}},
+		"decline":          {"unsubscribe", "nope", "cancel", []string{"unsubscribe"}},
+		"cancel any flow":  {"schedule", "cancel", "cancel", []string{"schedule"}},
+		"cancel uppercase": {"forget-me", "CANCEL", "cancel", []string{"forget-me"}},
+		"restore schedule": {"subscribe", "Yes", "subscribe", []string{"restore"}},
+		"start over":       {"subscribe", "no", "subscribe", []string{"fresh"}},
+	}
+	for name, tt := range accepted {
+		t.Run(name, func(t *testing.T) {
+			cmd, args, ok := parseAnswer(tt.Flow, tt.Message)
+			assert.Equal(t, ok, true)
+			assert.Equal(t, cmd, tt.Cmd)
+			assert.Equal(t, args, tt.Args)
+		})
+	}
+
+	// These aren't answers, so they're treated as commands instead.
+	rejected := map[string]struct{ Flow, Message string }{
+		"empty":           {"schedule", "  "},
+		"not a day":       {"schedule", "mon someday"},
+		"a whole command": {"schedule", "schedule mon"},
+		"yes, but":        {"unsubscribe", "yes please"},
+		"other command":   {"forget-me", "status"},
+		"unknown flow":    {"skip", "yes"},
+		"maybe":           {"subscribe", "maybe"},
+	}
+	for name, tt := range rejected {
+		t.Run(name, func(t *testing.T) {
+			_, _, ok := parseAnswer(tt.Flow, tt.Message)
+			assert.Equal(t, ok, false)
+		})
+	}
+}
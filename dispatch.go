@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
-	"strconv"
+	"maps"
+	"slices"
 	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/recursecenter/pairing-bot/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (pl *PairingLogic) dispatch(ctx context.Context, cmd string, cmdArgs []string, rec *store.Recurser) (string, error) {
@@ -20,10 +26,10 @@ func (pl *PairingLogic) dispatch(ctx context.Context, cmd string, cmdArgs []stri
 		return pl.SetSchedule(ctx, rec, cmdArgs)
 
 	case "subscribe":
-		return pl.Subscribe(ctx, rec)
+		return pl.Subscribe(ctx, rec, cmdArgs)
 
 	case "unsubscribe":
-		return pl.Unsubscribe(ctx, rec)
+		return pl.Unsubscribe(ctx, rec, cmdArgs)
 
 	case "skip":
 		return pl.SkipTomorrow(ctx, rec)
@@ -39,11 +45,9 @@ func (pl *PairingLogic) dispatch(ctx context.Context, cmd string, cmdArgs []stri
 		return pl.AddReview(ctx, rec, content)
 
 	case "get-reviews":
-		numReviews := 5
-		if len(cmdArgs) > 0 {
-			numReviews, _ = strconv.Atoi(cmdArgs[0])
-		}
-		return pl.GetReviews(ctx, numReviews)
+		// parseCmd already checked these, so this can't fail.
+		args, _ := parseReviewsArgs(cmdArgs)
+		return pl.GetReviews(ctx, rec, args)
 
 	case "cookie":
 		return cookieClubMessage, nil
@@ -57,6 +61,43 @@ func (pl *PairingLogic) dispatch(ctx context.Context, cmd string, cmdArgs []stri
 	case "thanks":
 		return youreWelcomeMessage, nil
 
+	case "stay":
+		return pl.Stay(ctx, rec)
+
+	case "partners":
+		return pl.SetPartners(ctx, rec, cmdArgs[0])
+
+	case "interests":
+		if len(cmdArgs) == 0 {
+			return pl.ShowInterests(ctx, rec)
+		}
+		return pl.SetInterests(ctx, rec, cmdArgs[0])
+
+	case "my-reviews":
+		return pl.MyReviews(ctx, rec)
+
+	case "edit-review":
+		return pl.EditReview(ctx, rec, cmdArgs[0], cmdArgs[1])
+
+	case "delete-review":
+		return pl.DeleteReview(ctx, rec, cmdArgs[0])
+
+	case "my-data":
+		return pl.MyData(ctx, rec)
+
+	case "forget-me":
+		return pl.ForgetMe(ctx, rec, cmdArgs)
+
+	case "admin":
+		return pl.Admin(ctx, rec, cmdArgs)
+
+	// These only come from answers to questions (see answer), not parseCmd.
+	case "cancel":
+		return cancelMessage(cmdArgs[0]), nil
+
+	case "expired":
+		return expiredMessage(cmdArgs[0]), nil
+
 	default:
 		// this won't execute because all input has been sanitized
 		// by parseCmd() and all cases are handled explicitly above
@@ -69,53 +110,285 @@ func (pl *PairingLogic) SetSchedule(ctx context.Context, rec *store.Recurser, da
 		return notSubscribedMessage, nil
 	}
 
-	rec.Schedule = store.NewSchedule(days)
+	if len(days) == 0 {
+		return pl.ask(ctx, rec, "schedule", "Which days would you like to pair on? Reply with the days, like `mon wed fri`.")
+	}
 
-	if err := store.Recursers(pl.db).Set(ctx, rec.ID, rec); err != nil {
+	if err := store.Recursers(pl.db).SetSchedule(ctx, rec.ID, store.NewSchedule(days)); err != nil {
 		return writeErrorMessage, err
 	}
 	return "Awesome, your new schedule's been set! You can check it with `status`.", nil
 }
 
-func (pl *PairingLogic) Subscribe(ctx context.Context, rec *store.Recurser) (string, error) {
+// Subscribe starts matching the user. People who unsubscribed before still
+// have their old preferences, so they're asked whether to bring back their old
+// schedule first.
+func (pl *PairingLogic) Subscribe(ctx context.Context, rec *store.Recurser, args []string) (string, error) {
 	if rec.IsSubscribed {
 		return "You're already subscribed! Use `schedule` to set your schedule.", nil
 	}
 
+	restorable := rec.Unsubscribed && len(scheduledDays(rec.Schedule)) > 0
+	if restorable && len(args) == 0 {
+		return pl.ask(ctx, rec, "subscribe", fmt.Sprintf("Welcome back! Last time, you paired on **%s**. Would you like to restore that schedule? Reply `yes` to restore it, or `no` to start over with every weekday.", formatSchedule(rec.Schedule)))
+	}
+
 	atRC, err := pl.recurse.IsCurrentlyAtRC(ctx, rec.ID)
 	if err != nil {
 		log.Printf("Could not read currently-at-RC data from RC API: %s", err)
 		return readErrorMessage, err
 	}
 
-	rec.CurrentlyAtRC = atRC
+	if !rec.Unsubscribed {
+		// There's no record yet, so write a whole new one.
+		rec.Schedule = store.DefaultSchedule()
+		rec.CurrentlyAtRC = atRC
+		if rec.Partners == "" {
+			rec.Partners = store.PartnersAnyone
+		}
+		rec.IsSkippingTomorrow = false
+
+		if err = store.Recursers(pl.db).Set(ctx, rec.ID, rec); err != nil {
+			log.Printf("Could not update recurser in database: %s", err)
+			return writeErrorMessage, err
+		}
+		return subscribeMessage, nil
+	}
 
-	if err = store.Recursers(pl.db).Set(ctx, rec.ID, rec); err != nil {
+	restoring := restorable && args[0] == "restore"
+
+	var schedule map[string]bool
+	if !restoring {
+		schedule = store.DefaultSchedule()
+	}
+
+	if err := store.Recursers(pl.db).Resubscribe(ctx, rec.ID, atRC, schedule); err != nil {
 		log.Printf("Could not update recurser in database: %s", err)
 		return writeErrorMessage, err
 	}
+
+	if restoring {
+		return fmt.Sprintf(resubscribeMessage, formatSchedule(rec.Schedule)), nil
+	}
 	return subscribeMessage, nil
 }
 
-func (pl *PairingLogic) Unsubscribe(ctx context.Context, rec *store.Recurser) (string, error) {
+// Unsubscribe stops matching the user, once they've confirmed that they want
+// to.
+func (pl *PairingLogic) Unsubscribe(ctx context.Context, rec *store.Recurser, args []string) (string, error) {
 	if !rec.IsSubscribed {
 		return notSubscribedMessage, nil
 	}
 
-	if err := store.Recursers(pl.db).Delete(ctx, rec.ID); err != nil {
+	if len(args) == 0 {
+		return pl.ask(ctx, rec, "unsubscribe", "Are you sure you want to unsubscribe? I'll stop finding pairing partners for you. Reply `yes` or `no`.")
+	}
+
+	if err := store.Recursers(pl.db).Unsubscribe(ctx, rec.ID); err != nil {
 		return writeErrorMessage, err
 	}
 	return unsubscribeMessage, nil
 }
 
-func (pl *PairingLogic) SkipTomorrow(ctx context.Context, rec *store.Recurser) (string, error) {
+// Stay keeps the user subscribed as an alum when they leave RC.
+func (pl *PairingLogic) Stay(ctx context.Context, rec *store.Recurser) (string, error) {
 	if !rec.IsSubscribed {
 		return notSubscribedMessage, nil
 	}
 
-	rec.IsSkippingTomorrow = true
+	if err := store.Recursers(pl.db).SetAlumni(ctx, rec.ID, true); err != nil {
+		return writeErrorMessage, err
+	}
+	return "You got it! I'll keep matching you after your batch ends. If you change your mind, you can `unsubscribe` any time.", nil
+}
 
-	if err := store.Recursers(pl.db).Set(ctx, rec.ID, rec); err != nil {
+// SetPartners sets whether the user wants to be matched with alumni, people
+// currently at RC, or anyone.
+func (pl *PairingLogic) SetPartners(ctx context.Context, rec *store.Recurser, partners string) (string, error) {
+	if !rec.IsSubscribed {
+		return notSubscribedMessage, nil
+	}
+
+	if err := store.Recursers(pl.db).SetPartners(ctx, rec.ID, partners); err != nil {
+		return writeErrorMessage, err
+	}
+	return fmt.Sprintf("Got it! From now on, I'll match you with %s.", describePartners(partners)), nil
+}
+
+// maxInterestsLength keeps interests short enough to fit in a match message.
+const maxInterestsLength = 500
+
+// SetInterests sets what the user would like to pair on, which is shared with
+// their partners when they're matched.
+func (pl *PairingLogic) SetInterests(ctx context.Context, rec *store.Recurser, interests string) (string, error) {
+	if !rec.IsSubscribed {
+		return notSubscribedMessage, nil
+	}
+
+	if n := utf8.RuneCountInString(interests); n > maxInterestsLength {
+		return fmt.Sprintf("That's a bit long! Please keep your interests under %d characters (you sent %d).", maxInterestsLength, n), nil
+	}
+
+	if err := store.Recursers(pl.db).SetInterests(ctx, rec.ID, interests); err != nil {
+		return writeErrorMessage, err
+	}
+	return "Thanks! I'll share your interests with your pairing partners.", nil
+}
+
+// ShowInterests tells the user what they've said they'd like to pair on.
+func (pl *PairingLogic) ShowInterests(ctx context.Context, rec *store.Recurser) (string, error) {
+	if !rec.IsSubscribed {
+		return notSubscribedMessage, nil
+	}
+
+	if rec.Interests == "" {
+		return "You haven't told me what you'd like to pair on yet! Send something like `interests Rust, compilers, and anything with graphs`.", nil
+	}
+	return fmt.Sprintf("You'd like to pair on: %s", rec.Interests), nil
+}
+
+// describePartners finishes the sentence "I'll match you with ...".
+func describePartners(partners string) string {
+	switch partners {
+	case store.PartnersAlumniOnly:
+		return "RC alumni only"
+	case store.PartnersCurrentOnly:
+		return "people currently at RC only"
+	default:
+		return "anyone, whether they're at RC now or alumni"
+	}
+}
+
+// MyData sends the user a JSON dump of everything stored about them.
+func (pl *PairingLogic) MyData(ctx context.Context, rec *store.Recurser) (string, error) {
+	data, err := store.Privacy(pl.db).Collect(ctx, rec.ID, rec.Email)
+	if err != nil {
+		return readErrorMessage, err
+	}
+
+	if data.Empty() {
+		return "I don't have anything stored about you!", nil
+	}
+
+	// Show each document in its own code block so that a long history can be
+	// split across messages without breaking any of them up.
+	var blocks []string
+	add := func(label string, v any) error {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, fmt.Sprintf("%s:\n```json\n%s\n```", label, b))
+		return nil
+	}
+
+	if data.Recurser != nil {
+		if err := add("Your subscription", data.Recurser); err != nil {
+			return readErrorMessage, err
+		}
+	}
+	for i, review := range data.Reviews {
+		if err := add(fmt.Sprintf("Review %d of %d", i+1, len(data.Reviews)), review); err != nil {
+			return readErrorMessage, err
+		}
+	}
+	for i, msg := range data.Messages {
+		if err := add(fmt.Sprintf("Message %d of %d", i+1, len(data.Messages)), msg); err != nil {
+			return readErrorMessage, err
+		}
+	}
+	if data.Onboarding != nil {
+		if err := add("Your onboarding progress", data.Onboarding); err != nil {
+			return readErrorMessage, err
+		}
+	}
+	if data.Conversation != nil {
+		if err := add("The question I'm waiting for you to answer", data.Conversation); err != nil {
+			return readErrorMessage, err
+		}
+	}
+
+	messages := splitMessage("Here's everything I have stored about you:", blocks, maxMessageLength)
+	return pl.replyInParts(ctx, rec.ID, messages)
+}
+
+// replyInParts returns a lone message as the reply. When there are more, it
+// sends each one as a separate DM instead, and returns a short summary as the
+// reply.
+func (pl *PairingLogic) replyInParts(ctx context.Context, userID int64, messages []string) (string, error) {
+	if len(messages) == 1 {
+		return messages[0], nil
+	}
+
+	for _, msg := range messages {
+		if err := pl.zulip.SendUserMessage(ctx, []int64{userID}, msg); err != nil {
+			return "Something went sideways while sending that to you. Please try again later!", err
+		}
+	}
+	return fmt.Sprintf("That's everything! It took %d messages to send it all.", len(messages)), nil
+}
+
+// ForgetMe permanently deletes everything stored about the user: their
+// record, their reviews, and the messages that record who they paired with
+// (or, for match messages their partner shares, their part in them). Only
+// their ID is kept, so that they aren't introduced to Pairing Bot again.
+// Because this can't be undone, it only happens once the user confirms.
+func (pl *PairingLogic) ForgetMe(ctx context.Context, rec *store.Recurser, args []string) (string, error) {
+	privacy := store.Privacy(pl.db)
+
+	if len(args) == 0 {
+		data, err := privacy.Collect(ctx, rec.ID, rec.Email)
+		if err != nil {
+			return readErrorMessage, err
+		}
+
+		if data.Empty() {
+			return "I don't have anything stored about you, so there's nothing to forget!", nil
+		}
+
+		var items []string
+		if data.Recurser != nil {
+			items = append(items, "your subscription and schedule")
+		}
+		if n := len(data.Reviews); n > 0 {
+			items = append(items, fmt.Sprintf("%d %s", n, plural(n, "review", "reviews")))
+		}
+		if n := len(data.Messages); n > 0 {
+			items = append(items, fmt.Sprintf("%d %s I've sent you", n, plural(n, "message", "messages")))
+		}
+		if data.Onboarding != nil {
+			items = append(items, "your onboarding progress")
+		}
+		if data.Conversation != nil {
+			items = append(items, "the question I'm waiting for you to answer")
+		}
+
+		return pl.ask(ctx, rec, "forget-me", fmt.Sprintf(forgetMeConfirmMessage, strings.Join(items, ", ")))
+	}
+
+	counts, err := privacy.Forget(ctx, rec.ID, rec.Email)
+	if err != nil {
+		return writeErrorMessage, err
+	}
+
+	total := 0
+	for _, n := range counts {
+		total += n
+	}
+	if total == 0 {
+		return "I don't have anything stored about you, so there's nothing to forget!", nil
+	}
+
+	log.Printf("Forgot user %d: deleted %v", rec.ID, counts)
+	return "Done! I've deleted everything I knew about you. If you ever want to come back, just `subscribe`.", nil
+}
+
+func (pl *PairingLogic) SkipTomorrow(ctx context.Context, rec *store.Recurser) (string, error) {
+	if !rec.IsSubscribed {
+		return notSubscribedMessage, nil
+	}
+
+	if err := store.Recursers(pl.db).SetSkippingTomorrow(ctx, rec.ID, true); err != nil {
 		return writeErrorMessage, err
 	}
 	return `Tomorrow: cancelled. I feel you. **I will not match you** for pairing tomorrow <3`, nil
@@ -125,9 +398,8 @@ func (pl *PairingLogic) UnskipTomorrow(ctx context.Context, rec *store.Recurser)
 	if !rec.IsSubscribed {
 		return notSubscribedMessage, nil
 	}
-	rec.IsSkippingTomorrow = false
 
-	if err := store.Recursers(pl.db).Set(ctx, rec.ID, rec); err != nil {
+	if err := store.Recursers(pl.db).SetSkippingTomorrow(ctx, rec.ID, false); err != nil {
 		return writeErrorMessage, err
 	}
 	return "Tomorrow: uncancelled! Heckin *yes*! **I will match you** for pairing tomorrow :)", nil
@@ -138,18 +410,6 @@ func (pl *PairingLogic) Status(ctx context.Context, rec *store.Recurser) (string
 		return notSubscribedMessage, nil
 	}
 
-	// this particular days list is for sorting and printing the
-	// schedule correctly, since it's stored in a map in all lowercase
-	var daysList = []string{
-		"Monday",
-		"Tuesday",
-		"Wednesday",
-		"Thursday",
-		"Friday",
-		"Saturday",
-		"Sunday",
-	}
-
 	// get their current name
 	whoami := rec.Name
 
@@ -161,55 +421,321 @@ func (pl *PairingLogic) Status(ctx context.Context, rec *store.Recurser) (string
 		skipStr = " not "
 	}
 
-	// make a sorted list of their schedule
-	var schedule []string
-	for _, day := range daysList {
-		// this line is a little wild, sorry. it looks so weird because we
-		// have to do type assertion on both interface types
-		if rec.Schedule[strings.ToLower(day)] {
-			schedule = append(schedule, day)
-		}
+	scheduleStr := formatSchedule(rec.Schedule)
+
+	var alumniStr string
+	switch {
+	case rec.IsAlum():
+		alumniStr = "You're subscribed as an alum"
+	case rec.Alumni:
+		alumniStr = "You'll stay subscribed after your batch ends"
+	default:
+		alumniStr = "You'll be unsubscribed at the end of your batch (send `stay` to keep pairing)"
+	}
+
+	interestsStr := "You haven't shared any interests (see `interests`)"
+	if rec.Interests != "" {
+		interestsStr = "You'd like to pair on: " + rec.Interests
+	}
+
+	return fmt.Sprintf("* You're %v\n* You're scheduled for pairing on **%v**\n* **You're%vset to skip** pairing tomorrow\n* You'll be matched with %v\n* %v\n* %v", whoami, scheduleStr, skipStr, describePartners(rec.Partners), alumniStr, interestsStr), nil
+}
+
+// formatSchedule lists the scheduled days in a sentence, like "Mondays,
+// Wednesdays, and Fridays".
+func formatSchedule(schedule map[string]bool) string {
+	days := scheduledDays(schedule)
+	if len(days) == 0 {
+		return "no days"
 	}
+
 	// make a lil nice-lookin schedule string
 	var scheduleStr string
-	for i := range schedule[:len(schedule)-1] {
-		scheduleStr += schedule[i] + "s, "
+	for i := range days[:len(days)-1] {
+		scheduleStr += days[i] + "s, "
+	}
+	if len(days) > 1 {
+		scheduleStr += "and " + days[len(days)-1] + "s"
+	} else {
+		scheduleStr += days[0] + "s"
 	}
-	if len(schedule) > 1 {
-		scheduleStr += "and " + schedule[len(schedule)-1] + "s"
-	} else if len(schedule) == 1 {
-		scheduleStr += schedule[0] + "s"
+	return scheduleStr
+}
+
+// scheduledDays returns the capitalized names of the scheduled days, in order
+// starting with Monday.
+func scheduledDays(schedule map[string]bool) []string {
+	// this particular days list is for sorting and printing the
+	// schedule correctly, since it's stored in a map in all lowercase
+	var daysList = []string{
+		"Monday",
+		"Tuesday",
+		"Wednesday",
+		"Thursday",
+		"Friday",
+		"Saturday",
+		"Sunday",
 	}
 
-	return fmt.Sprintf("* You're %v\n* You're scheduled for pairing on **%v**\n* **You're%vset to skip** pairing tomorrow", whoami, scheduleStr, skipStr), nil
+	var days []string
+	for _, day := range daysList {
+		if schedule[strings.ToLower(day)] {
+			days = append(days, day)
+		}
+	}
+	return days
 }
 
 func (pl *PairingLogic) AddReview(ctx context.Context, rec *store.Recurser, content string) (string, error) {
 	currentTimestamp := time.Now().Unix()
 
-	err := store.Reviews(pl.db).Insert(ctx, store.Review{
+	id, err := store.Reviews(pl.db).Insert(ctx, store.Review{
 		Content:   content,
 		Timestamp: currentTimestamp,
 		Email:     rec.Email,
+		AuthorID:  rec.ID,
+		Status:    store.ReviewPending,
 	})
 	if err != nil {
 		log.Println("Encountered an error when trying to save a review: ", err)
 		return writeErrorMessage, err
 	}
 
-	return "Thank you for sharing your review with pairing bot!", nil
+	// The review is safely stored, so a failed notification only delays it
+	// until a maintainer checks `admin reviews pending`.
+	notification := fmt.Sprintf(reviewSubmittedMessage, rec.Name, id, quote(content), id, id)
+	if err := pl.notifyMaintainers(ctx, notification); err != nil {
+		log.Printf("Could not notify maintainers about review %s: %s", id, err)
+	}
+
+	return "Thank you for sharing your review with pairing bot! It'll show up in `get-reviews` once a maintainer has had a look at it.", nil
 }
 
-func (pl *PairingLogic) GetReviews(ctx context.Context, numReviews int) (string, error) {
-	lastN, err := store.Reviews(pl.db).GetLastN(ctx, numReviews)
+// MyReviews lists the user's reviews with their IDs and moderation status.
+func (pl *PairingLogic) MyReviews(ctx context.Context, rec *store.Recurser) (string, error) {
+	reviews, err := store.Reviews(pl.db).ListByAuthor(ctx, rec.ID, rec.Email)
 	if err != nil {
-		log.Printf("Encountered an error when trying to fetch the last %v reviews: %v", numReviews, err)
 		return readErrorMessage, err
 	}
 
-	response := "Here are some reviews of pairing bot:\n"
-	for _, rev := range lastN {
-		response += "* \"" + rev.Content + "\"!\n"
+	if len(reviews) == 0 {
+		return "You haven't written any reviews yet. Share one with `add-review`!", nil
+	}
+
+	var blocks []string
+	for _, review := range reviews {
+		submitted := time.Unix(review.Timestamp, 0).UTC().Format(time.DateOnly)
+		blocks = append(blocks, fmt.Sprintf("**%s** (%s, submitted %s):\n%s", review.ID, review.Status, submitted, quote(review.Content)))
+	}
+	blocks = append(blocks, "Use `edit-review <id> <new review>` to change one, or `delete-review <id>` to remove it.")
+
+	messages := splitMessage("Here are your reviews:", blocks, maxMessageLength)
+	return pl.replyInParts(ctx, rec.ID, messages)
+}
+
+// EditReview replaces the content of one of the user's reviews. The edited
+// review needs to be approved again before it's shown.
+func (pl *PairingLogic) EditReview(ctx context.Context, rec *store.Recurser, id, content string) (string, error) {
+	err := store.Reviews(pl.db).Edit(ctx, id, rec.ID, rec.Email, content)
+	if msg, ok := reviewChangeError(id, err); ok {
+		return msg, nil
+	} else if err != nil {
+		return writeErrorMessage, err
+	}
+
+	notification := fmt.Sprintf(reviewEditedMessage, rec.Name, id, quote(content), id, id)
+	if err := pl.notifyMaintainers(ctx, notification); err != nil {
+		log.Printf("Could not notify maintainers about review %s: %s", id, err)
+	}
+
+	return "Your review has been updated! It'll show up in `get-reviews` again once a maintainer has had a look at it.", nil
+}
+
+// DeleteReview removes one of the user's reviews.
+func (pl *PairingLogic) DeleteReview(ctx context.Context, rec *store.Recurser, id string) (string, error) {
+	err := store.Reviews(pl.db).Delete(ctx, id, rec.ID, rec.Email)
+	if msg, ok := reviewChangeError(id, err); ok {
+		return msg, nil
+	} else if err != nil {
+		return writeErrorMessage, err
+	}
+	return "Your review has been deleted.", nil
+}
+
+// reviewChangeError returns the reply for errors that mean the user can't
+// change the review, as opposed to errors talking to the database.
+func reviewChangeError(id string, err error) (string, bool) {
+	switch {
+	case status.Code(err) == codes.NotFound:
+		return fmt.Sprintf("I couldn't find a review with ID `%s`. Use `my-reviews` to see yours.", id), true
+	case errors.Is(err, store.ErrNotAuthor):
+		return "You can only change reviews that you wrote. Use `my-reviews` to see yours.", true
+	default:
+		return "", false
+	}
+}
+
+// GetReviews shows a page of approved reviews, newest first. Long pages are
+// split across several messages.
+func (pl *PairingLogic) GetReviews(ctx context.Context, rec *store.Recurser, args reviewsArgs) (string, error) {
+	reviews, next, err := store.Reviews(pl.db).ListApproved(ctx, store.ReviewQuery{
+		Since:    args.Since,
+		Until:    args.Until,
+		After:    args.After,
+		PageSize: args.Count,
+	})
+	if err != nil {
+		log.Printf("Encountered an error when trying to fetch reviews (%s): %v", args, err)
+		return readErrorMessage, err
+	}
+
+	if len(reviews) == 0 {
+		if args.After != "" {
+			return "There are no more reviews to show!", nil
+		}
+		return "I couldn't find any reviews of pairing bot. Be the first with `add-review`!", nil
+	}
+
+	var blocks []string
+	for _, rev := range reviews {
+		blocks = append(blocks, "* \""+rev.Content+"\"!")
+	}
+	if next != "" {
+		more := args
+		more.After = next
+		blocks = append(blocks, fmt.Sprintf("For more, send `%s`.", more))
+	}
+
+	messages := splitMessage("Here are some reviews of pairing bot:", blocks, maxMessageLength)
+	return pl.replyInParts(ctx, rec.ID, messages)
+}
+
+// Admin runs a maintainer-only command.
+func (pl *PairingLogic) Admin(ctx context.Context, rec *store.Recurser, args []string) (string, error) {
+	if !isMaintainer(rec.ID) {
+		return notMaintainerMessage, nil
+	}
+
+	switch args[0] {
+	case "jobs":
+		return pl.JobHistory(ctx)
+
+	case "schema":
+		return pl.SchemaReport(ctx)
+
+	case "reviews":
+		return pl.ModerateReviews(ctx, rec, args[1:])
+
+	case "refresh":
+		pl.recurse.Invalidate()
+		return "Done! I'll fetch fresh batches and profiles from the Recurse API the next time I need them.", nil
+
+	default:
+		// parseAdminCmd only accepts the subcommands handled above.
+		return helpMessage, nil
+	}
+}
+
+// JobHistory lists the most recent cron job runs and their outcomes.
+func (pl *PairingLogic) JobHistory(ctx context.Context) (string, error) {
+	runs, err := store.JobRuns(pl.db).ListRecent(ctx, 20)
+	if err != nil {
+		return readErrorMessage, err
+	}
+
+	if len(runs) == 0 {
+		return "No jobs have run yet.", nil
+	}
+
+	response := "Here are the most recent job runs:\n"
+	for _, run := range runs {
+		started := time.Unix(run.StartedAt, 0).UTC()
+		line := fmt.Sprintf("* `%s` started %s by %s (attempt %d): **%s**", run.Job, started.Format(time.DateTime), run.TriggeredBy, run.Attempt, run.Status)
+		if run.EndedAt != 0 {
+			line += fmt.Sprintf(" after %s", time.Duration(run.EndedAt-run.StartedAt)*time.Second)
+		}
+		if run.Error != "" {
+			line += fmt.Sprintf(" with error `%s`", run.Error)
+		}
+		response += line + "\n"
+	}
+	return response, nil
+}
+
+// SchemaReport lists the stored documents that are outdated or can't be
+// decoded, without changing any of them. Run "pbctl migrate" to upgrade them.
+func (pl *PairingLogic) SchemaReport(ctx context.Context) (string, error) {
+	report, err := store.Migrations(pl.db).Run(ctx, true)
+	if err != nil {
+		return readErrorMessage, err
+	}
+
+	response := "Documents written with an older schema:\n"
+	for _, collection := range slices.Sorted(maps.Keys(report.Scanned)) {
+		response += fmt.Sprintf("* `%s`: %d of %d\n", collection, report.Outdated[collection], report.Scanned[collection])
+	}
+
+	if len(report.Failures) == 0 {
+		response += "\nEvery document decoded successfully."
+		return response, nil
+	}
+
+	response += fmt.Sprintf("\n%d documents could not be decoded:\n", len(report.Failures))
+	for _, f := range report.Failures {
+		response += fmt.Sprintf("* `%s`: `%s`\n", f.Path, f.Error)
 	}
 	return response, nil
 }
+
+// ModerateReviews lists pending reviews or approves or rejects one of them.
+func (pl *PairingLogic) ModerateReviews(ctx context.Context, rec *store.Recurser, args []string) (string, error) {
+	reviews := store.Reviews(pl.db)
+
+	switch args[0] {
+	case "pending":
+		pending, err := reviews.ListByStatus(ctx, store.ReviewPending)
+		if err != nil {
+			return readErrorMessage, err
+		}
+
+		if len(pending) == 0 {
+			return "There are no reviews waiting for moderation.", nil
+		}
+
+		var blocks []string
+		for _, review := range pending {
+			submitted := time.Unix(review.Timestamp, 0).UTC().Format(time.DateOnly)
+			blocks = append(blocks, fmt.Sprintf("**%s** (submitted %s):\n%s", review.ID, submitted, quote(review.Content)))
+		}
+		blocks = append(blocks, "Use `admin reviews approve <id>` or `admin reviews reject <id>` to decide.")
+
+		messages := splitMessage("These reviews are waiting for moderation:", blocks, maxMessageLength)
+		return pl.replyInParts(ctx, rec.ID, messages)
+
+	case "approve", "reject":
+		id := args[1]
+		decision := store.ReviewApproved
+		if args[0] == "reject" {
+			decision = store.ReviewRejected
+		}
+
+		err := reviews.Moderate(ctx, id, decision, rec.ID)
+		if status.Code(err) == codes.NotFound {
+			return fmt.Sprintf("I couldn't find a review with ID `%s`.", id), nil
+		} else if err != nil {
+			return writeErrorMessage, err
+		}
+
+		log.Printf("Review %s was %s by %d", id, decision, rec.ID)
+		return fmt.Sprintf("Review `%s` is now %s.", id, decision), nil
+
+	default:
+		// parseAdminReviewsCmd only accepts the actions handled above.
+		return helpMessage, nil
+	}
+}
+
+// quote formats the text as a Zulip quote block.
+func quote(text string) string {
+	return "```quote\n" + text + "\n```"
+}
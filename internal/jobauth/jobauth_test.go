@@ -0,0 +1,76 @@
+package jobauth_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/jobauth"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+
+	signed := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/match", nil)
+		jobauth.Sign(req, "fake-secret", "Your Name", now)
+		return req
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		operator, signedAt, err := jobauth.Verify(signed(), "fake-secret", now.Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, operator, "Your Name")
+		assert.Equal(t, signedAt, now.Truncate(time.Second))
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/match", nil)
+		_, _, err := jobauth.Verify(req, "fake-secret", now)
+		assert.ErrorIs(t, err, jobauth.ErrUnsigned)
+	})
+
+	t.Run("wrong secret", func(t *testing.T) {
+		_, _, err := jobauth.Verify(signed(), "other-secret", now)
+		assert.ErrorIs(t, err, jobauth.ErrBadSignature)
+	})
+
+	t.Run("no secret", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/match", nil)
+		jobauth.Sign(req, "", "Your Name", now)
+
+		_, _, err := jobauth.Verify(req, "", now)
+		assert.ErrorIs(t, err, jobauth.ErrBadSignature)
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, _, err := jobauth.Verify(signed(), "fake-secret", now.Add(jobauth.MaxAge+time.Second))
+		assert.ErrorIs(t, err, jobauth.ErrExpiredSignature)
+	})
+
+	t.Run("different path", func(t *testing.T) {
+		req := signed()
+		req.URL.Path = "/endofbatch"
+
+		_, _, err := jobauth.Verify(req, "fake-secret", now)
+		assert.ErrorIs(t, err, jobauth.ErrBadSignature)
+	})
+
+	t.Run("added query", func(t *testing.T) {
+		req := signed()
+		req.URL.RawQuery = "force=true"
+
+		_, _, err := jobauth.Verify(req, "fake-secret", now)
+		assert.ErrorIs(t, err, jobauth.ErrBadSignature)
+	})
+
+	t.Run("different operator", func(t *testing.T) {
+		req := signed()
+		req.Header.Set(jobauth.OperatorHeader, "Someone Else")
+
+		_, _, err := jobauth.Verify(req, "fake-secret", now)
+		assert.ErrorIs(t, err, jobauth.ErrBadSignature)
+	})
+}
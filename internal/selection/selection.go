@@ -0,0 +1,231 @@
+// Package selection decides who and what Pairing Bot's jobs act on. The jobs
+// and pbctl's dry runs both use it, so a dry run always previews what the job
+// would really do.
+package selection
+
+import (
+	"cmp"
+	"math/rand"
+	"slices"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/recurse"
+	"github.com/recursecenter/pairing-bot/store"
+	"github.com/recursecenter/pairing-bot/zulip"
+)
+
+const week = 7 * 24 * time.Hour
+
+// OffboardWarningWindow is how long before the end of a batch to warn its
+// subscribers that they'll be offboarded.
+const OffboardWarningWindow = 5 * 24 * time.Hour
+
+// OnboardingWindow is how long after starting at RC someone is still new
+// enough to be introduced to Pairing Bot.
+const OnboardingWindow = 2 * week
+
+// CanMessage returns whether a match message can be sent to the account. ok
+// is false if there's no such account, like when it's been deleted outright.
+func CanMessage(account zulip.Account, ok bool) bool {
+	return ok && account.IsActive && !account.IsBot
+}
+
+// Messageable sorts the recursers by whether their Zulip accounts can be
+// messaged, keeping their order. accounts holds every account Zulip knows
+// about.
+func Messageable(recursers []store.Recurser, accounts map[int64]zulip.Account) (valid, invalid []store.Recurser) {
+	for _, rec := range recursers {
+		account, ok := accounts[rec.ID]
+		if CanMessage(account, ok) {
+			valid = append(valid, rec)
+		} else {
+			invalid = append(invalid, rec)
+		}
+	}
+	return valid, invalid
+}
+
+// Shuffle puts the recursers in a random order derived from seed, so that a
+// day's matches can be reproduced from the seed later if needed.
+func Shuffle(recursers []store.Recurser, seed int64) {
+	rand.New(rand.NewSource(seed)).Shuffle(len(recursers), func(i, j int) {
+		recursers[i], recursers[j] = recursers[j], recursers[i]
+	})
+}
+
+// PairUp matches the recursers two at a time, in order, while respecting
+// everyone's partner preferences. People with a preference have fewer
+// possible partners, so they're matched first. Whoever is left over is
+// returned as unmatched.
+func PairUp(recursers []store.Recurser) (pairs [][2]store.Recurser, unmatched []store.Recurser) {
+	recursers = slices.Clone(recursers)
+	slices.SortStableFunc(recursers, func(a, b store.Recurser) int {
+		return cmp.Compare(pickiness(a), pickiness(b))
+	})
+
+	matched := make([]bool, len(recursers))
+	for i := range recursers {
+		if matched[i] {
+			continue
+		}
+
+		for j := i + 1; j < len(recursers); j++ {
+			if !matched[j] && recursers[i].CanPairWith(&recursers[j]) {
+				matched[i], matched[j] = true, true
+				pairs = append(pairs, [2]store.Recurser{recursers[i], recursers[j]})
+				break
+			}
+		}
+
+		if !matched[i] {
+			unmatched = append(unmatched, recursers[i])
+		}
+	}
+
+	return pairs, unmatched
+}
+
+// pickiness sorts people who will only pair with some recursers ahead of
+// people who will pair with anyone.
+func pickiness(r store.Recurser) int {
+	if r.Partners == store.PartnersAlumniOnly || r.Partners == store.PartnersCurrentOnly {
+		return 0
+	}
+	return 1
+}
+
+// EndingBatches returns the batches that end within OffboardWarningWindow of
+// now, whose subscribers are due an offboarding warning.
+func EndingBatches(batches []recurse.Batch, now time.Time) []recurse.Batch {
+	var ending []recurse.Batch
+	for _, batch := range batches {
+		if batch.EndsWithin(now, OffboardWarningWindow) {
+			ending = append(ending, batch)
+		}
+	}
+	return ending
+}
+
+// OffboardWarnings sorts the subscribers in an ending batch into those to warn
+// and those staying on at RC after the batch (for another batch, or to work
+// there), in the order of the batch's profiles. Alumni who asked to stay
+// subscribed aren't in either list, since they won't be offboarded, and
+// neither is anyone whose Zulip account is deactivated, since the warning
+// couldn't reach them.
+func OffboardWarnings(batch recurse.Batch, profiles []recurse.Profile, subscribers []store.Recurser) (warn, staying []store.Recurser) {
+	byID := make(map[int64]store.Recurser, len(subscribers))
+	for _, rec := range subscribers {
+		byID[rec.ID] = rec
+	}
+
+	lastDay := time.Time(batch.EndDate)
+	for _, p := range profiles {
+		rec, ok := byID[p.ZulipID]
+		if !ok || rec.Alumni || rec.Deactivated {
+			continue
+		}
+
+		if stayingOn(p, lastDay) {
+			staying = append(staying, rec)
+		} else {
+			warn = append(warn, rec)
+		}
+	}
+	return warn, staying
+}
+
+// stayingOn returns whether the Recurser is still at RC after lastDay, or
+// coming back within a week of it. Batches end on a Friday and the next ones
+// start on a Monday, so someone going straight into another batch has a gap
+// of a weekend.
+func stayingOn(p recurse.Profile, lastDay time.Time) bool {
+	for _, s := range p.Stints {
+		start, end := time.Time(s.StartDate), time.Time(s.EndDate)
+		if (end.IsZero() || end.After(lastDay)) && !start.After(lastDay.Add(week)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AtRC returns the Zulip IDs of the Recursers who are currently at RC.
+func AtRC(active []recurse.Profile) map[int64]bool {
+	atRC := make(map[int64]bool, len(active))
+	for _, p := range active {
+		atRC[p.ZulipID] = true
+	}
+	return atRC
+}
+
+// Leavers returns the subscribers who were at RC as of the last end of batch
+// but aren't any more, sorted into those to offboard and the alumni who asked
+// to stay subscribed.
+func Leavers(subscribers []store.Recurser, atRC map[int64]bool) (offboard, keep []store.Recurser) {
+	for _, rec := range subscribers {
+		if !rec.CurrentlyAtRC || atRC[rec.ID] {
+			continue
+		}
+
+		if rec.Alumni {
+			keep = append(keep, rec)
+		} else {
+			offboard = append(offboard, rec)
+		}
+	}
+	return offboard, keep
+}
+
+// Synced returns the subscriber's synced fields as they should be now. ok is
+// false if they have no Zulip account, and atRC is false if they aren't
+// currently at RC, in which case profile is ignored.
+func Synced(rec store.Recurser, account zulip.Account, ok bool, profile recurse.Profile, atRC bool, now time.Time) store.SyncedFields {
+	fields := rec.Synced()
+
+	if ok {
+		fields.Name = account.FullName
+		fields.Email = account.Email
+	}
+	fields.Deactivated = !CanMessage(account, ok)
+
+	if stint, ok := profile.StintOn(now); atRC && ok && stint.Batch != nil {
+		fields.Batch = stint.Batch.Name
+	}
+	return fields
+}
+
+// Newcomer returns the stint of someone who started a batch within
+// OnboardingWindow of now. ok is false for anyone else.
+func Newcomer(p recurse.Profile, now time.Time) (stint recurse.Stint, ok bool) {
+	stint, ok = p.StintOn(now)
+	if !ok || stint.Batch == nil || now.Sub(time.Time(stint.StartDate)) > OnboardingWindow {
+		return recurse.Stint{}, false
+	}
+	return stint, true
+}
+
+// A Welcome is a batch that's due to be welcomed.
+type Welcome struct {
+	Batch recurse.Batch
+	// Mini batches get a shorter welcome.
+	Mini bool
+	// ExpiresAt is when the welcome would be too late to be worth posting.
+	ExpiresAt time.Time
+}
+
+// Welcomes returns the batches that should be welcomed now: full batches in
+// their second week, and mini batches in their only week. Batches overlap, so
+// there can be more than one.
+func Welcomes(batches []recurse.Batch, now time.Time) []Welcome {
+	var due []Welcome
+	for _, batch := range batches {
+		start := time.Time(batch.StartDate)
+
+		switch {
+		case batch.IsMini() && batch.IsFirstWeek(now):
+			due = append(due, Welcome{Batch: batch, Mini: true, ExpiresAt: start.Add(week)})
+		case !batch.IsMini() && batch.IsSecondWeek(now):
+			due = append(due, Welcome{Batch: batch, ExpiresAt: start.Add(2 * week)})
+		}
+	}
+	return due
+}
@@ -0,0 +1,310 @@
+package selection_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/selection"
+	"github.com/recursecenter/pairing-bot/recurse"
+	"github.com/recursecenter/pairing-bot/store"
+	"github.com/recursecenter/pairing-bot/zulip"
+)
+
+func TestMessageable(t *testing.T) {
+	recursers := []store.Recurser{{ID: 1}, {ID: 2}, {ID: 3}, {ID: 4}, {ID: 5}}
+	accounts := map[int64]zulip.Account{
+		1: {UserID: 1, IsActive: true},
+		2: {UserID: 2, IsActive: false},
+		3: {UserID: 3, IsActive: true, IsBot: true},
+		// 4 was deleted outright.
+		5: {UserID: 5, IsActive: true},
+	}
+
+	valid, invalid := selection.Messageable(recursers, accounts)
+
+	assert.Equal(t, valid, []store.Recurser{{ID: 1}, {ID: 5}})
+	assert.Equal(t, invalid, []store.Recurser{{ID: 2}, {ID: 3}, {ID: 4}})
+}
+
+func TestPairUp(t *testing.T) {
+	current := func(id int64, partners string) store.Recurser {
+		return store.Recurser{ID: id, CurrentlyAtRC: true, Partners: partners}
+	}
+	alum := func(id int64, partners string) store.Recurser {
+		return store.Recurser{ID: id, Alumni: true, Partners: partners}
+	}
+
+	ids := func(pairs [][2]store.Recurser, unmatched []store.Recurser) ([][2]int64, []int64) {
+		var pairIDs [][2]int64
+		for _, p := range pairs {
+			pairIDs = append(pairIDs, [2]int64{p[0].ID, p[1].ID})
+		}
+		var unmatchedIDs []int64
+		for _, r := range unmatched {
+			unmatchedIDs = append(unmatchedIDs, r.ID)
+		}
+		return pairIDs, unmatchedIDs
+	}
+
+	t.Run("anyone pairs in order", func(t *testing.T) {
+		pairs, unmatched := ids(selection.PairUp([]store.Recurser{
+			current(1, store.PartnersAnyone),
+			alum(2, store.PartnersAnyone),
+			current(3, ""),
+			current(4, store.PartnersAnyone),
+			alum(5, store.PartnersAnyone),
+		}))
+
+		assert.Equal(t, pairs, [][2]int64{{1, 2}, {3, 4}})
+		assert.Equal(t, unmatched, []int64{5})
+	})
+
+	t.Run("preferences are mutual", func(t *testing.T) {
+		pairs, unmatched := ids(selection.PairUp([]store.Recurser{
+			current(1, store.PartnersAnyone),
+			alum(2, store.PartnersAnyone),
+			current(3, store.PartnersCurrentOnly),
+			alum(4, store.PartnersAlumniOnly),
+		}))
+
+		// The picky ones go first, and each finds someone who fits.
+		assert.Equal(t, pairs, [][2]int64{{3, 1}, {4, 2}})
+		assert.Equal(t, len(unmatched), 0)
+	})
+
+	t.Run("nobody suitable", func(t *testing.T) {
+		pairs, unmatched := ids(selection.PairUp([]store.Recurser{
+			current(1, store.PartnersAlumniOnly),
+			current(2, store.PartnersAnyone),
+		}))
+
+		assert.Equal(t, len(pairs), 0)
+		assert.Equal(t, unmatched, []int64{1, 2})
+	})
+}
+
+func TestWelcomes(t *testing.T) {
+	date := func(s string) recurse.Datestamp {
+		d, err := time.Parse(time.DateOnly, s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return recurse.Datestamp(d)
+	}
+
+	// Most recent first, like the Recurse API returns them.
+	batches := []recurse.Batch{
+		{ID: 4, Name: "Summer 2, 2024", StartDate: date("2024-06-24"), EndDate: date("2024-09-13")},
+		{ID: 3, Name: "Mini 1, 2024", StartDate: date("2024-06-24"), EndDate: date("2024-06-28")},
+		{ID: 2, Name: "Summer 1, 2024", StartDate: date("2024-05-13"), EndDate: date("2024-08-02")},
+		{ID: 1, Name: "Spring 2, 2024", StartDate: date("2024-04-01"), EndDate: date("2024-06-21")},
+	}
+
+	welcomed := func(now string) []int64 {
+		var ids []int64
+		for _, w := range selection.Welcomes(batches, must(time.Parse(time.RFC3339, now))) {
+			ids = append(ids, w.Batch.ID)
+		}
+		return ids
+	}
+
+	// The mini batch is welcomed during its only week, while Summer 2 waits
+	// for its second week.
+	assert.Equal(t, welcomed("2024-06-25T18:00:00Z"), []int64{3})
+	assert.Equal(t, welcomed("2024-07-02T18:00:00Z"), []int64{4})
+
+	// Summer 1 overlaps with Spring 2, but only Summer 1 is new.
+	assert.Equal(t, welcomed("2024-05-21T18:00:00Z"), []int64{2})
+
+	assert.Equal(t, len(welcomed("2024-06-11T18:00:00Z")), 0)
+
+	mini := selection.Welcomes(batches, must(time.Parse(time.RFC3339, "2024-06-25T18:00:00Z")))[0]
+	assert.Equal(t, mini.Mini, true)
+	assert.Equal(t, mini.ExpiresAt, time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC))
+}
+
+func TestOffboardWarnings(t *testing.T) {
+	batch := recurse.Batch{ID: 4, Name: "Summer 2, 2024", StartDate: datestamp("2024-06-24"), EndDate: datestamp("2024-09-13")}
+
+	t.Run("window", func(t *testing.T) {
+		for now, ending := range map[string]bool{
+			"2024-09-07T12:00:00Z": false,
+			"2024-09-08T12:00:00Z": true,
+			"2024-09-12T12:00:00Z": true,
+			// The last day isn't over yet.
+			"2024-09-13T18:00:00Z": true,
+			"2024-09-14T01:00:00Z": false,
+		} {
+			got := selection.EndingBatches([]recurse.Batch{batch}, must(time.Parse(time.RFC3339, now)))
+			assert.Equal(t, len(got) == 1, ending)
+		}
+	})
+
+	inBatch := recurse.Stint{StartDate: batch.StartDate, EndDate: batch.EndDate, Batch: &batch}
+	// The next batch starts the Monday after.
+	nextBatch := recurse.Stint{StartDate: datestamp("2024-09-16"), EndDate: datestamp("2024-12-06")}
+	nextYear := recurse.Stint{StartDate: datestamp("2025-01-06"), EndDate: datestamp("2025-03-28")}
+	employment := recurse.Stint{Type: "employment", StartDate: datestamp("2024-01-08")}
+
+	profiles := []recurse.Profile{
+		{ZulipID: 1, Stints: []recurse.Stint{inBatch}},
+		{ZulipID: 2, Stints: []recurse.Stint{inBatch}},
+		{ZulipID: 3, Stints: []recurse.Stint{inBatch, nextBatch}},
+		{ZulipID: 4, Stints: []recurse.Stint{inBatch}},
+		// 5 isn't subscribed.
+		{ZulipID: 5, Stints: []recurse.Stint{inBatch}},
+		{ZulipID: 7, Stints: []recurse.Stint{inBatch, nextYear}},
+		{ZulipID: 8, Stints: []recurse.Stint{employment, inBatch}},
+	}
+	subscribers := []store.Recurser{
+		{ID: 1, CurrentlyAtRC: true},
+		{ID: 2, CurrentlyAtRC: true, Alumni: true},
+		{ID: 3, CurrentlyAtRC: true},
+		{ID: 4, CurrentlyAtRC: true, Deactivated: true},
+		// 6 is subscribed, but wasn't in the batch.
+		{ID: 6, CurrentlyAtRC: true},
+		{ID: 7, CurrentlyAtRC: true},
+		{ID: 8, CurrentlyAtRC: true},
+	}
+
+	for name, tt := range map[string]struct {
+		ID      int64
+		Warn    bool
+		Staying bool
+	}{
+		"leaving":                {ID: 1, Warn: true},
+		"alum who asked to stay": {ID: 2},
+		"staying another batch":  {ID: 3, Staying: true},
+		"deactivated":            {ID: 4},
+		"not subscribed":         {ID: 5},
+		"in another batch":       {ID: 6},
+		"back much later":        {ID: 7, Warn: true},
+		"working at RC":          {ID: 8, Staying: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			warn, staying := selection.OffboardWarnings(batch, profiles, subscribers)
+			has := func(recursers []store.Recurser) bool {
+				return slices.ContainsFunc(recursers, func(r store.Recurser) bool { return r.ID == tt.ID })
+			}
+			assert.Equal(t, has(warn), tt.Warn)
+			assert.Equal(t, has(staying), tt.Staying)
+		})
+	}
+}
+
+func TestLeavers(t *testing.T) {
+	atRC := selection.AtRC([]recurse.Profile{{ZulipID: 1}, {ZulipID: 4}})
+
+	subscribers := []store.Recurser{
+		{ID: 1, CurrentlyAtRC: true},
+		{ID: 2, CurrentlyAtRC: true},
+		{ID: 3, CurrentlyAtRC: true, Alumni: true},
+		{ID: 4, CurrentlyAtRC: false},
+		{ID: 5, CurrentlyAtRC: false},
+		{ID: 6, CurrentlyAtRC: true, Deactivated: true},
+	}
+	offboard, keep := selection.Leavers(subscribers, atRC)
+
+	for name, tt := range map[string]struct {
+		ID       int64
+		Offboard bool
+		Keep     bool
+	}{
+		"still at RC":            {ID: 1},
+		"just left":              {ID: 2, Offboard: true},
+		"alum who asked to stay": {ID: 3, Keep: true},
+		"back at RC":             {ID: 4},
+		"subscribed as an alum":  {ID: 5},
+		"deactivated":            {ID: 6, Offboard: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			has := func(recursers []store.Recurser) bool {
+				return slices.ContainsFunc(recursers, func(r store.Recurser) bool { return r.ID == tt.ID })
+			}
+			assert.Equal(t, has(offboard), tt.Offboard)
+			assert.Equal(t, has(keep), tt.Keep)
+		})
+	}
+}
+
+func TestSynced(t *testing.T) {
+	now := must(time.Parse(time.RFC3339, "2024-07-02T18:00:00Z"))
+
+	batch := recurse.Batch{ID: 4, Name: "Summer 2, 2024"}
+	profile := recurse.Profile{Stints: []recurse.Stint{
+		{StartDate: datestamp("2024-06-24"), EndDate: datestamp("2024-09-13"), Batch: &batch},
+	}}
+	employee := recurse.Profile{Stints: []recurse.Stint{
+		{Type: "employment", StartDate: datestamp("2024-01-08")},
+	}}
+
+	rec := store.Recurser{ID: 1, Name: "Old Name", Email: "old@recurse.example.net", Batch: "Spring 2, 2024"}
+	active := zulip.Account{UserID: 1, FullName: "New Name", Email: "new@recurse.example.net", IsActive: true}
+
+	withFields := func(f func(*store.SyncedFields)) store.SyncedFields {
+		fields := rec.Synced()
+		f(&fields)
+		return fields
+	}
+
+	for name, tt := range map[string]struct {
+		Rec     store.Recurser
+		Account zulip.Account
+		OK      bool
+		Profile recurse.Profile
+		AtRC    bool
+		Want    store.SyncedFields
+	}{
+		"nothing changed": {
+			Rec:     store.Recurser{ID: 1, Name: "New Name", Email: "new@recurse.example.net", Batch: "Summer 2, 2024"},
+			Account: active, OK: true, Profile: profile, AtRC: true,
+			Want: store.SyncedFields{Name: "New Name", Email: "new@recurse.example.net", Batch: "Summer 2, 2024"},
+		},
+		"new name, email, and batch": {
+			Rec: rec, Account: active, OK: true, Profile: profile, AtRC: true,
+			Want: store.SyncedFields{Name: "New Name", Email: "new@recurse.example.net", Batch: "Summer 2, 2024"},
+		},
+		"alumni keep their last batch": {
+			Rec: rec, Account: active, OK: true,
+			Want: withFields(func(f *store.SyncedFields) { f.Name, f.Email = active.FullName, active.Email }),
+		},
+		"working at RC": {
+			Rec: rec, Account: active, OK: true, Profile: employee, AtRC: true,
+			Want: withFields(func(f *store.SyncedFields) { f.Name, f.Email = active.FullName, active.Email }),
+		},
+		"deactivated": {
+			Rec: rec, Account: zulip.Account{UserID: 1, FullName: "New Name", Email: "new@recurse.example.net"}, OK: true,
+			Want: withFields(func(f *store.SyncedFields) { f.Name, f.Email, f.Deactivated = active.FullName, active.Email, true }),
+		},
+		"bot": {
+			Rec: rec, Account: zulip.Account{UserID: 1, FullName: "New Name", Email: "new@recurse.example.net", IsActive: true, IsBot: true}, OK: true,
+			Want: withFields(func(f *store.SyncedFields) { f.Name, f.Email, f.Deactivated = active.FullName, active.Email, true }),
+		},
+		"deleted account": {
+			Rec:  rec,
+			Want: withFields(func(f *store.SyncedFields) { f.Deactivated = true }),
+		},
+		"reactivated": {
+			Rec: store.Recurser{ID: 1, Name: "New Name", Email: "new@recurse.example.net", Deactivated: true}, Account: active, OK: true,
+			Want: store.SyncedFields{Name: "New Name", Email: "new@recurse.example.net"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got := selection.Synced(tt.Rec, tt.Account, tt.OK, tt.Profile, tt.AtRC, now)
+			assert.Equal(t, got, tt.Want)
+		})
+	}
+}
+
+func datestamp(s string) recurse.Datestamp {
+	return recurse.Datestamp(must(time.ParseInLocation(time.DateOnly, s, time.UTC)))
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
@@ -7,9 +7,11 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"time"
 
 	"cloud.google.com/go/firestore"
 	"github.com/recursecenter/pairing-bot/recurse"
+	"github.com/recursecenter/pairing-bot/scheduler"
 	"github.com/recursecenter/pairing-bot/store"
 	"github.com/recursecenter/pairing-bot/zulip"
 )
@@ -99,21 +101,73 @@ func main() {
 		panic(err)
 	}
 
+	// Subscribing looks up whether someone is at RC, which would otherwise
+	// page through the whole directory every time. The directory rarely
+	// changes except at the start and end of batches.
+	recurseCache := recurse.NewCache(recurseClient, 15*time.Minute, time.Hour)
+
 	pl := &PairingLogic{
 		db:      db,
-		recurse: recurseClient,
+		recurse: recurseCache,
 		zulip:   zulipClient,
 
 		version:       appVersion,
 		welcomeStream: welcomeStream,
 	}
 
-	http.HandleFunc("/", http.NotFound)                 // will this handle anything that's not defined?
-	http.HandleFunc("/webhooks", pl.handle)             // from zulip
-	http.HandleFunc("/match", cron(pl.Match))           // from GCP- daily
-	http.HandleFunc("/endofbatch", cron(pl.EndOfBatch)) // from GCP- weekly
-	http.HandleFunc("/welcome", cron(pl.Welcome))       // from GCP- weekly
-	http.HandleFunc("/checkin", cron(pl.Checkin))       // from GCP- weekly
+	// Each job runs at most once per period, no matter how many times (or
+	// how concurrently) the scheduler triggers it.
+	runs := store.JobRuns(db)
+	day := 24 * time.Hour
+
+	matchJob := recordRuns(runs, "match", day, pl.Match)
+	endOfBatchJob := recordRuns(runs, "endofbatch", day, pl.EndOfBatch)
+	offboardWarningJob := recordRuns(runs, "offboardwarning", day, pl.WarnOffboarding)
+	welcomeJob := recordRuns(runs, "welcome", day, pl.Welcome)
+	checkinJob := recordRuns(runs, "checkin", day, pl.Checkin)
+	syncJob := recordRuns(runs, "sync", day, pl.Sync)
+	onboardJob := recordRuns(runs, "onboard", day, pl.Onboard)
+	outboxJob := recordRuns(runs, "outbox", 15*time.Minute, pl.RetryOutbox)
+
+	// Operators can also trigger jobs by signing requests with this secret.
+	triggerSecret := func(ctx context.Context) (string, error) {
+		return store.Secrets(db).Get(ctx, "job_trigger_secret")
+	}
+
+	http.HandleFunc("/", http.NotFound)                                          // will this handle anything that's not defined?
+	http.HandleFunc("/webhooks", pl.handle)                                      // from zulip
+	http.HandleFunc("/match", cron(matchJob, triggerSecret))                     // from GCP- daily
+	http.HandleFunc("/endofbatch", cron(endOfBatchJob, triggerSecret))           // from GCP- weekly
+	http.HandleFunc("/offboardwarning", cron(offboardWarningJob, triggerSecret)) // from GCP- daily
+	http.HandleFunc("/welcome", cron(welcomeJob, triggerSecret))                 // from GCP- weekly
+	http.HandleFunc("/checkin", cron(checkinJob, triggerSecret))                 // from GCP- weekly
+	http.HandleFunc("/sync", cron(syncJob, triggerSecret))                       // from GCP- daily
+	http.HandleFunc("/onboard", cron(onboardJob, triggerSecret))                 // from GCP- daily
+	http.HandleFunc("/outbox", cron(outboxJob, triggerSecret))                   // from GCP- every 15 minutes
+
+	// Outside of App Engine, nothing calls the cron endpoints. Run the same
+	// schedule (see cron.yaml) in-process instead. The job run leases keep
+	// this safe even if both schedulers are active, and the recorded runs
+	// keep a restarted process from catching up on runs that already
+	// finished.
+	if os.Getenv("PB_SCHEDULER") == "true" {
+		sched, err := scheduler.New([]scheduler.Job{
+			{Name: "match", Schedule: "0 4 * * *", CatchUp: 6 * time.Hour, Finished: finishedRuns(runs, "match", day), Run: matchJob},
+			{Name: "endofbatch", Schedule: "0 16 * * sat", CatchUp: 24 * time.Hour, Finished: finishedRuns(runs, "endofbatch", day), Run: endOfBatchJob},
+			{Name: "offboardwarning", Schedule: "0 15 * * *", CatchUp: 6 * time.Hour, Finished: finishedRuns(runs, "offboardwarning", day), Run: offboardWarningJob},
+			{Name: "welcome", Schedule: "0 18 * * tue", CatchUp: 6 * time.Hour, Finished: finishedRuns(runs, "welcome", day), Run: welcomeJob},
+			{Name: "checkin", Schedule: "0 18 * * thu", CatchUp: 6 * time.Hour, Finished: finishedRuns(runs, "checkin", day), Run: checkinJob},
+			{Name: "sync", Schedule: "0 2 * * *", CatchUp: 2 * time.Hour, Finished: finishedRuns(runs, "sync", day), Run: syncJob},
+			{Name: "onboard", Schedule: "0 17 * * *", CatchUp: 6 * time.Hour, Finished: finishedRuns(runs, "onboard", day), Run: onboardJob},
+			{Name: "outbox", Schedule: "*/15 * * * *", CatchUp: 15 * time.Minute, Finished: finishedRuns(runs, "outbox", 15*time.Minute), Run: outboxJob},
+		})
+		if err != nil {
+			log.Panic(err)
+		}
+
+		log.Printf("Starting in-process job scheduler")
+		go sched.Run(ctx)
+	}
 
 	port := os.Getenv("PORT")
 	if port == "" {
@@ -3,6 +3,8 @@ package main
 import (
 	_ "embed"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 )
 
 //go:embed messages/odd_one_out.md
@@ -11,6 +13,12 @@ var oddOneOutMessage string
 //go:embed messages/matched.md
 var matchedMessage string
 
+//go:embed messages/rematched.md
+var rematchedMessage string
+
+//go:embed messages/partnerUnreachable.md
+var partnerUnreachableMessage string
+
 //go:embed messages/offboarded.md
 var offboardedMessage string
 
@@ -26,11 +34,147 @@ var helpMessage string
 //go:embed messages/subscribed.md
 var subscribeMessage string
 
+//go:embed messages/resubscribed.md
+var resubscribeMessage string
+
 //go:embed messages/unsubscribed.md
 var unsubscribeMessage string
 
+//go:embed messages/offboardWarning.md
+var offboardWarningMessage string
+
+//go:embed messages/reviewSubmitted.md
+var reviewSubmittedMessage string
+
+//go:embed messages/reviewEdited.md
+var reviewEditedMessage string
+
+//go:embed messages/forgetMeConfirm.md
+var forgetMeConfirmMessage string
+
+//go:embed messages/onboarding.md
+var onboardingMessage string
+
 const notSubscribedMessage string = "You're not subscribed to Pairing Bot <3"
 const youreWelcomeMessage string = "You're welcome!"
+const notMaintainerMessage string = "Sorry, only Pairing Bot maintainers can do that!"
 
 var writeErrorMessage = fmt.Sprintf("Something went sideways while writing to the database. You should probably ping %v", maintainersMention())
 var readErrorMessage = fmt.Sprintf("Something went sideways while reading from the database. You should probably ping %v", maintainersMention())
+
+// maxMessageLength is the longest message Zulip accepts, in characters.
+//
+// https://zulip.com/api/send-message#parameter-content
+const maxMessageLength = 10000
+
+// splitMessage joins the header and blocks into as few messages as fit within
+// limit characters each, keeping blocks whole where possible. Blocks that are
+// too long on their own are cut into pieces (see cutBlock).
+func splitMessage(header string, blocks []string, limit int) []string {
+	var messages []string
+	current := header
+
+	for _, block := range blocks {
+		if utf8.RuneCountInString(current)+utf8.RuneCountInString(block)+2 <= limit {
+			if current != "" {
+				current += "\n\n"
+			}
+			current += block
+			continue
+		}
+
+		if current != "" {
+			messages = append(messages, current)
+		}
+		current = ""
+
+		pieces := cutBlock(block, limit)
+		messages = append(messages, pieces[:len(pieces)-1]...)
+		current = pieces[len(pieces)-1]
+	}
+
+	if current != "" {
+		messages = append(messages, current)
+	}
+	return messages
+}
+
+// codeFence starts and ends a Markdown code block.
+const codeFence = "```"
+
+// cutBlock cuts the block into pieces of at most limit characters, after a
+// line where it can. A code block that's cut through is closed at the end of
+// one piece and opened again at the start of the next, so that the rest of
+// it still shows up as code.
+func cutBlock(block string, limit int) []string {
+	var pieces []string
+	runes := []rune(block)
+	opener := ""
+
+	for {
+		prefix := ""
+		if opener != "" {
+			prefix = opener + "\n"
+		}
+		room := limit - utf8.RuneCountInString(prefix)
+		closing := utf8.RuneCountInString("\n" + codeFence)
+		if room <= closing {
+			// The limit is too small to reopen the code block.
+			prefix, room = "", limit
+		}
+
+		if len(runes) <= room {
+			return append(pieces, prefix+string(runes))
+		}
+
+		n := cutAfterLine(runes, room)
+		if openFence(prefix+string(runes[:n])) != "" {
+			// Leave room to close the code block.
+			n = cutAfterLine(runes, room-closing)
+		}
+
+		piece := prefix + string(runes[:n])
+		runes = runes[n:]
+
+		opener = openFence(piece)
+		if opener != "" {
+			piece = strings.TrimSuffix(piece, "\n") + "\n" + codeFence
+		}
+		pieces = append(pieces, piece)
+	}
+}
+
+// cutAfterLine returns where to cut the runes to keep at most n of them:
+// after the last line break, or at n if there isn't one.
+func cutAfterLine(runes []rune, n int) int {
+	for i := n; i > 0; i-- {
+		if runes[i-1] == '\n' {
+			return i
+		}
+	}
+	return n
+}
+
+// openFence returns the line that opened the code block that the text ends
+// inside of, or "" if it doesn't end inside one.
+func openFence(text string) string {
+	opener := ""
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case opener == "" && strings.HasPrefix(line, codeFence):
+			opener = line
+		case opener != "" && line == codeFence:
+			opener = ""
+		}
+	}
+	return opener
+}
+
+// plural returns one or many depending on n.
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
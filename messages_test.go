@@ -0,0 +1,43 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+)
+
+func Test_splitMessage(t *testing.T) {
+	t.Run("everything fits", func(t *testing.T) {
+		got := splitMessage("Header:", []string{"one", "two"}, 100)
+		assert.Equal(t, got, []string{"Header:\n\none\n\ntwo"})
+	})
+
+	t.Run("blocks stay whole", func(t *testing.T) {
+		got := splitMessage("Header:", []string{"aaaa", "bbbb", "cccc"}, 15)
+		assert.Equal(t, got, []string{"Header:\n\naaaa", "bbbb\n\ncccc"})
+	})
+
+	t.Run("long blocks are cut", func(t *testing.T) {
+		got := splitMessage("", []string{"short", strings.Repeat("x", 25)}, 10)
+		assert.Equal(t, got, []string{"short", strings.Repeat("x", 10), strings.Repeat("x", 10), strings.Repeat("x", 5)})
+	})
+
+	t.Run("code blocks are reopened", func(t *testing.T) {
+		block := "Label:\n```json\n{\n  \"a\": 1,\n  \"b\": 2\n}\n```"
+		got := splitMessage("", []string{block}, 24)
+		assert.Equal(t, got, []string{
+			"Label:\n```json\n{\n```",
+			"```json\n  \"a\": 1,\n```",
+			"```json\n  \"b\": 2\n}\n```",
+		})
+		for _, message := range got {
+			assert.Equal(t, openFence(message), "")
+		}
+	})
+
+	t.Run("counts characters, not bytes", func(t *testing.T) {
+		got := splitMessage("", []string{"ééé", "ééé"}, 8)
+		assert.Equal(t, got, []string{"ééé\n\nééé"})
+	})
+}
@@ -2,29 +2,238 @@ package main
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
 	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/jobauth"
+	"github.com/recursecenter/pairing-bot/scheduler"
+	"github.com/recursecenter/pairing-bot/store"
 )
 
 // JobFunc is the type of function that can run as a cron job.
 type JobFunc func(context.Context) error
 
+// SecretFunc returns the current value of a shared secret.
+type SecretFunc func(context.Context) (string, error)
+
 // cron wraps a job function to make it an HTTP handler. The handler enforces
-// that requests originate from App Engine's Cron scheduler.
-func cron(job JobFunc) http.HandlerFunc {
+// that requests either originate from App Engine's Cron scheduler or are
+// signed by an operator with the shared trigger secret (see jobauth). If
+// triggerSecret is nil, only App Engine requests are allowed. Outside of App
+// Engine, every request must be signed.
+//
+// Every accepted request is logged with who triggered it.
+func cron(job JobFunc, triggerSecret SecretFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Check that the request is originating from within app engine
-		// https://cloud.google.com/appengine/docs/standard/go/scheduling-jobs-with-cron-yaml#validating_cron_requests
-		if r.Header.Get("X-Appengine-Cron") != "true" {
+		ctx := r.Context()
+
+		trigger, scheduled, err := authorizeTrigger(r, triggerSecret)
+		if err != nil {
+			slog.Warn("Rejected job trigger",
+				slog.String("path", r.URL.Path),
+				slog.String("remote_addr", r.RemoteAddr),
+				slog.Any("error", err),
+			)
 			http.NotFound(w, r)
 			return
 		}
 
-		err := job(r.Context())
-		if err != nil {
+		slog.Info("Job triggered",
+			slog.String("path", r.URL.Path),
+			slog.String("triggered_by", trigger),
+			slog.Time("scheduled", scheduled),
+			slog.String("remote_addr", r.RemoteAddr),
+		)
+
+		ctx = scheduler.WithScheduledTime(withTrigger(ctx, trigger), scheduled)
+
+		// Only operators can force a run, since their signature covers the
+		// query.
+		if triggeredByOperator(ctx) && r.URL.Query().Get("force") == "true" {
+			ctx = withForce(ctx)
+		}
+
+		err = job(ctx)
+
+		var skipped *skippedRunError
+		if errors.As(err, &skipped) {
+			http.Error(w, skipped.Error()+"; force it to run again", http.StatusConflict)
+			return
+		} else if err != nil {
 			slog.Error("Job failed", slog.Any("error", err))
 			w.WriteHeader(http.StatusInternalServerError)
 			return
 		}
 	}
 }
+
+// cloudSchedulerTimeHeader is set by App Engine cron to the time the request
+// was scheduled for, which stays the same when it retries the request.
+const cloudSchedulerTimeHeader = "X-CloudScheduler-ScheduleTime"
+
+// authorizeTrigger checks where the job request came from and returns a
+// description of the trigger for the audit log, along with the time the run
+// was scheduled for. Operators schedule their runs for when they sign them.
+func authorizeTrigger(r *http.Request, triggerSecret SecretFunc) (string, time.Time, error) {
+	// Check that the request is originating from within app engine
+	// https://cloud.google.com/appengine/docs/standard/go/scheduling-jobs-with-cron-yaml#validating_cron_requests
+	//
+	// App Engine strips this header from external requests, so it can only
+	// be trusted when running there. Anywhere else, anyone could set it.
+	if onAppEngine() && r.Header.Get("X-Appengine-Cron") == "true" {
+		scheduled, err := time.Parse(time.RFC3339, r.Header.Get(cloudSchedulerTimeHeader))
+		if err != nil {
+			scheduled = time.Now()
+		}
+		return "App Engine cron", scheduled, nil
+	}
+
+	if triggerSecret == nil {
+		return "", time.Time{}, jobauth.ErrUnsigned
+	}
+
+	secret, err := triggerSecret(r.Context())
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("fetch trigger secret: %w", err)
+	}
+
+	operator, signedAt, err := jobauth.Verify(r, secret, time.Now())
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return operatorTrigger + operator, signedAt, nil
+}
+
+// onAppEngine returns whether the server is running on App Engine, which sets
+// GAE_ENV in every instance's environment.
+//
+// https://cloud.google.com/appengine/docs/standard/go/runtime#environment_variables
+func onAppEngine() bool {
+	return os.Getenv("GAE_ENV") != ""
+}
+
+// triggerKey is the context key for the description of a job's trigger.
+type triggerKey struct{}
+
+// withTrigger records who triggered the job in the context.
+func withTrigger(ctx context.Context, trigger string) context.Context {
+	return context.WithValue(ctx, triggerKey{}, trigger)
+}
+
+// triggerFrom returns who triggered the job. Jobs without a recorded trigger
+// were started by the in-process scheduler.
+func triggerFrom(ctx context.Context) string {
+	if trigger, ok := ctx.Value(triggerKey{}).(string); ok {
+		return trigger
+	}
+	return "in-process scheduler"
+}
+
+// operatorTrigger starts the description of a job triggered by an operator,
+// followed by their name.
+const operatorTrigger = "operator "
+
+// triggeredByOperator returns whether an operator triggered the job, rather
+// than a scheduler.
+func triggeredByOperator(ctx context.Context) bool {
+	return strings.HasPrefix(triggerFrom(ctx), operatorTrigger)
+}
+
+// forceKey is the context key for whether an operator forced a job to run.
+type forceKey struct{}
+
+// withForce marks the job as forced, so that it runs even if it already ran
+// this period.
+func withForce(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forceKey{}, true)
+}
+
+// forcedFrom returns whether the job was forced to run.
+func forcedFrom(ctx context.Context) bool {
+	forced, _ := ctx.Value(forceKey{}).(bool)
+	return forced
+}
+
+// skippedRunError is returned to operators whose run recordRuns skipped,
+// since they asked for that run specifically.
+type skippedRunError struct {
+	Job       string
+	Status    string
+	Scheduled time.Time
+}
+
+func (e *skippedRunError) Error() string {
+	return fmt.Sprintf("job %q already %s for the period starting %s", e.Job, e.Status, e.Scheduled.UTC().Format(time.RFC3339))
+}
+
+// jobLease is how long a job run can go without finishing before another
+// attempt is allowed to take over, e.g., after the first one crashed.
+const jobLease = 15 * time.Minute
+
+// recordRuns wraps a job so that it runs at most once per period. Each run
+// takes a lease on its scheduled slot and records its outcome in the store.
+// Duplicate or overlapping triggers for the same slot return without running
+// the job. Schedulers retry and double-fire, so that's normal for them, but an
+// operator who triggered the run gets a skippedRunError instead.
+//
+// The slot is the time the run was scheduled for (see
+// scheduler.ScheduledTime), truncated to the period. That way, a late run
+// still counts against the period it was meant for. Runs without a scheduled
+// time are scheduled for when they start. A forced run (see withForce) gets a
+// slot of its own at the exact time it was scheduled, so it runs even if the
+// period's run already has.
+func recordRuns(runs *store.JobRunsClient, name string, period time.Duration, job JobFunc) JobFunc {
+	return func(ctx context.Context) error {
+		scheduled, ok := scheduler.ScheduledTime(ctx)
+		if !ok {
+			scheduled = time.Now()
+		}
+		if !forcedFrom(ctx) {
+			scheduled = scheduled.Truncate(period)
+		}
+
+		run, acquired, err := runs.Acquire(ctx, name, scheduled, jobLease, triggerFrom(ctx))
+		if err != nil {
+			return fmt.Errorf("acquire lease for job %q: %w", name, err)
+		}
+		if !acquired {
+			slog.Info("Skipping job that already ran or is running",
+				slog.String("job", name),
+				slog.String("status", run.Status),
+				slog.Time("scheduled", scheduled),
+			)
+			if triggeredByOperator(ctx) {
+				return &skippedRunError{Job: name, Status: run.Status, Scheduled: scheduled}
+			}
+			return nil
+		}
+
+		slog.Info("Starting job",
+			slog.String("job", name),
+			slog.Int("attempt", run.Attempt),
+			slog.Time("scheduled", scheduled),
+			slog.String("triggered_by", run.TriggeredBy),
+		)
+
+		jobErr := job(ctx)
+
+		// Record the outcome even if the request was cancelled.
+		if err := runs.Finish(context.WithoutCancel(ctx), run, jobErr); err != nil {
+			slog.Error("Could not record job outcome", slog.String("job", name), slog.Any("error", err))
+		}
+		return jobErr
+	}
+}
+
+// finishedRuns reports whether a run that recordRuns wrapped has already
+// succeeded, for the scheduler to check before catching up on it.
+func finishedRuns(runs *store.JobRunsClient, name string, period time.Duration) func(context.Context, time.Time) (bool, error) {
+	return func(ctx context.Context, scheduled time.Time) (bool, error) {
+		return runs.Succeeded(ctx, name, scheduled.Truncate(period))
+	}
+}
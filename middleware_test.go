@@ -3,21 +3,29 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/jobauth"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/scheduler"
+	"github.com/recursecenter/pairing-bot/store"
 )
 
 func Test_cron(t *testing.T) {
 	t.Run("run job for AppEngine", func(t *testing.T) {
+		t.Setenv("GAE_ENV", "standard")
+
 		// Arrange a cron job that tells us whether it ran.
 		ran := false
 		handler := cron(func(context.Context) error {
 			ran = true
 			return nil
-		})
+		}, nil)
 
 		// Prepare an AppEngine-sourced request.
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -39,7 +47,7 @@ func Test_cron(t *testing.T) {
 		handler := cron(func(context.Context) error {
 			t.Error("handler should not have run")
 			return nil
-		})
+		}, nil)
 
 		// Prepare a request from outside of AppEngine (no custom header).
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -54,11 +62,34 @@ func Test_cron(t *testing.T) {
 		assert.Equal(t, resp.StatusCode, 404)
 	})
 
+	t.Run("deny AppEngine header outside of AppEngine", func(t *testing.T) {
+		t.Setenv("GAE_ENV", "")
+
+		handler := cron(func(context.Context) error {
+			t.Error("handler should not have run")
+			return nil
+		}, nil)
+
+		// Anyone can set this header when App Engine isn't there to strip it.
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Appengine-Cron", "true")
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, resp.StatusCode, 404)
+	})
+
 	t.Run("report job failure", func(t *testing.T) {
+		t.Setenv("GAE_ENV", "standard")
+
 		// Arrange a cron job that errors.
 		handler := cron(func(context.Context) error {
 			return errors.New("test error")
-		})
+		}, nil)
 
 		// Prepare an AppEngine-sourced request.
 		req := httptest.NewRequest(http.MethodGet, "/", nil)
@@ -74,3 +105,183 @@ func Test_cron(t *testing.T) {
 		assert.Equal(t, resp.StatusCode, 500)
 	})
 }
+
+func Test_cron_signed(t *testing.T) {
+	secret := func(context.Context) (string, error) {
+		return "fake-secret", nil
+	}
+
+	t.Run("run job for signed request", func(t *testing.T) {
+		var triggeredBy string
+		handler := cron(func(ctx context.Context) error {
+			triggeredBy = triggerFrom(ctx)
+			return nil
+		}, secret)
+
+		req := httptest.NewRequest(http.MethodPost, "/match", nil)
+		jobauth.Sign(req, "fake-secret", "Your Name", time.Now())
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, resp.StatusCode, 200)
+		assert.Equal(t, triggeredBy, "operator Your Name")
+	})
+
+	t.Run("force signed request", func(t *testing.T) {
+		for target, expected := range map[string]bool{
+			"/match":            false,
+			"/match?force=true": true,
+		} {
+			var forced bool
+			handler := cron(func(ctx context.Context) error {
+				forced = forcedFrom(ctx)
+				return nil
+			}, secret)
+
+			req := httptest.NewRequest(http.MethodPost, target, nil)
+			jobauth.Sign(req, "fake-secret", "Your Name", time.Now())
+
+			w := httptest.NewRecorder()
+			handler(w, req)
+
+			assert.Equal(t, w.Code, 200)
+			assert.Equal(t, forced, expected)
+		}
+	})
+
+	t.Run("report skipped run", func(t *testing.T) {
+		handler := cron(func(context.Context) error {
+			return &skippedRunError{Job: "match", Status: store.JobSucceeded, Scheduled: time.Date(2024, 5, 20, 0, 0, 0, 0, time.UTC)}
+		}, secret)
+
+		req := httptest.NewRequest(http.MethodPost, "/match", nil)
+		jobauth.Sign(req, "fake-secret", "Your Name", time.Now())
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		assert.Equal(t, w.Code, 409)
+		assert.Equal(t, w.Body.String(), `job "match" already succeeded for the period starting 2024-05-20T00:00:00Z; force it to run again`+"\n")
+	})
+
+	t.Run("deny request with bad signature", func(t *testing.T) {
+		handler := cron(func(context.Context) error {
+			t.Error("handler should not have run")
+			return nil
+		}, secret)
+
+		req := httptest.NewRequest(http.MethodPost, "/match", nil)
+		jobauth.Sign(req, "wrong-secret", "Your Name", time.Now())
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, resp.StatusCode, 404)
+	})
+
+	t.Run("deny signed request without configured secret", func(t *testing.T) {
+		handler := cron(func(context.Context) error {
+			t.Error("handler should not have run")
+			return nil
+		}, nil)
+
+		req := httptest.NewRequest(http.MethodPost, "/match", nil)
+		jobauth.Sign(req, "fake-secret", "Your Name", time.Now())
+
+		w := httptest.NewRecorder()
+		handler(w, req)
+
+		resp := w.Result()
+		defer resp.Body.Close()
+
+		assert.Equal(t, resp.StatusCode, 404)
+	})
+}
+
+func Test_recordRuns(t *testing.T) {
+	ctx := context.Background()
+	client := pbtest.FirestoreClient(t, ctx)
+	runs := store.JobRuns(client)
+
+	t.Run("run once per period", func(t *testing.T) {
+		count := 0
+		job := recordRuns(runs, fmt.Sprintf("test-%d", pbtest.RandInt64(t)), 24*time.Hour, func(context.Context) error {
+			count++
+			return nil
+		})
+
+		assert.NoError(t, job(ctx))
+		assert.NoError(t, job(ctx))
+
+		assert.Equal(t, count, 1)
+	})
+
+	t.Run("late run counts against its scheduled period", func(t *testing.T) {
+		count := 0
+		job := recordRuns(runs, fmt.Sprintf("test-%d", pbtest.RandInt64(t)), 24*time.Hour, func(context.Context) error {
+			count++
+			return nil
+		})
+
+		// Yesterday's run, and a catch-up for it that only starts today.
+		yesterday := time.Now().Truncate(24*time.Hour).AddDate(0, 0, -1).Add(16 * time.Hour)
+		assert.NoError(t, job(scheduler.WithScheduledTime(ctx, yesterday)))
+		assert.NoError(t, job(scheduler.WithScheduledTime(ctx, yesterday.Add(time.Hour))))
+		assert.Equal(t, count, 1)
+
+		// Today's own run still happens.
+		assert.NoError(t, job(ctx))
+		assert.Equal(t, count, 2)
+	})
+
+	t.Run("operators hear about skipped runs", func(t *testing.T) {
+		count := 0
+		job := recordRuns(runs, fmt.Sprintf("test-%d", pbtest.RandInt64(t)), 24*time.Hour, func(context.Context) error {
+			count++
+			return nil
+		})
+
+		assert.NoError(t, job(ctx))
+
+		operatorCtx := withTrigger(ctx, "operator Your Name")
+		var skipped *skippedRunError
+		if !errors.As(job(operatorCtx), &skipped) {
+			t.Error("expected the operator's run to be reported as skipped")
+		}
+		assert.Equal(t, count, 1)
+
+		// Forcing it runs the job again, but only once per request.
+		forcedCtx := scheduler.WithScheduledTime(withForce(operatorCtx), time.Now())
+		assert.NoError(t, job(forcedCtx))
+		if !errors.As(job(forcedCtx), &skipped) {
+			t.Error("expected a repeat of the forced run to be skipped")
+		}
+		assert.Equal(t, count, 2)
+	})
+
+	t.Run("retry failed run", func(t *testing.T) {
+		count := 0
+		job := recordRuns(runs, fmt.Sprintf("test-%d", pbtest.RandInt64(t)), 24*time.Hour, func(context.Context) error {
+			count++
+			if count == 1 {
+				return errors.New("test error")
+			}
+			return nil
+		})
+
+		if err := job(ctx); err == nil {
+			t.Error("expected first run to fail")
+		}
+		assert.NoError(t, job(ctx))
+		assert.NoError(t, job(ctx))
+
+		assert.Equal(t, count, 2)
+	})
+}
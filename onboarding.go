@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"unicode/utf8"
+
+	"github.com/recursecenter/pairing-bot/internal/selection"
+	"github.com/recursecenter/pairing-bot/store"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// The follow-up prompts for each onboarding step. These are added to the end
+// of the bot's reply to the command that finished the previous step.
+const (
+	onboardingSubscribeReminder = "By the way, you haven't subscribed yet! Reply `subscribe` whenever you'd like to start pairing."
+	onboardingSchedulePrompt    = "Next up: which days would you like to pair? Reply with something like `schedule mon wed fri`, or skip this to keep pairing every weekday."
+	onboardingInterestsPrompt   = "Last thing: what would you like to pair on? Reply with something like `interests Rust, compilers, and anything with graphs`, and I'll share it with your partners."
+	onboardingDoneMessage       = "You're all set! I'll send you your first match on one of your scheduled days. Send `status` to check your settings any time."
+)
+
+// Onboard introduces Pairing Bot to people who have just started at RC, with
+// a direct message that invites them to subscribe. Each person is only
+// introduced once, and only if they've never used Pairing Bot before.
+func (pl *PairingLogic) Onboard(ctx context.Context) error {
+	now := time.Now()
+
+	profiles, err := pl.recurse.ActiveRecursers(ctx)
+	if err != nil {
+		return fmt.Errorf("get active Recursers: %w", err)
+	}
+
+	introduced := 0
+	for _, p := range profiles {
+		stint, ok := selection.Newcomer(p, now)
+		if !ok {
+			continue
+		}
+
+		// Anyone with a record has already found their way here.
+		_, err := store.Recursers(pl.db).Get(ctx, p.ZulipID)
+		if err == nil {
+			continue
+		} else if status.Code(err) != codes.NotFound {
+			return fmt.Errorf("look up %s (ID %d): %w", p.Name, p.ZulipID, err)
+		}
+
+		// Anyone who asked to be forgotten shouldn't hear from us again.
+		progress, err := store.Onboarding(pl.db).Get(ctx, p.ZulipID)
+		if err != nil {
+			return fmt.Errorf("look up onboarding for %s (ID %d): %w", p.Name, p.ZulipID, err)
+		}
+		if progress != nil && progress.Step == store.OnboardingForgotten {
+			continue
+		}
+
+		// The outbox source makes sure the introduction is only queued once,
+		// even if recording the onboarding progress fails below.
+		source := fmt.Sprintf("onboarding %d", p.ZulipID)
+		queued, err := store.Outbox(pl.db).Enqueue(ctx, source, []store.OutboxMessage{{
+			Recipients: []int64{p.ZulipID},
+			Content:    fmt.Sprintf(onboardingMessage, stint.Batch.Name),
+			ExpiresAt:  now.Add(selection.OnboardingWindow).Unix(),
+		}})
+		if err != nil {
+			return fmt.Errorf("queue introduction for %s (ID %d): %w", p.Name, p.ZulipID, err)
+		}
+
+		if _, err := store.Onboarding(pl.db).Start(ctx, p.ZulipID, stint.Batch.Name); err != nil {
+			return fmt.Errorf("start onboarding for %s (ID %d): %w", p.Name, p.ZulipID, err)
+		}
+
+		if err := pl.deliverPendingFrom(ctx, source); err != nil {
+			return err
+		}
+		if queued {
+			introduced++
+		}
+	}
+
+	log.Printf("Introduced Pairing Bot to %d new Recursers", introduced)
+	return nil
+}
+
+// continueOnboarding adds the next onboarding prompt to the response for
+// people who are partway through being introduced to Pairing Bot, and records
+// their progress.
+func (pl *PairingLogic) continueOnboarding(ctx context.Context, rec *store.Recurser, cmd string, cmdArgs []string, response string) string {
+	// Forgetting someone leaves only a marker in place of their onboarding
+	// progress.
+	if cmd == "forget-me" {
+		return response
+	}
+
+	onboarding := store.Onboarding(pl.db)
+	progress, err := onboarding.Get(ctx, rec.ID)
+	if err != nil {
+		log.Printf("Could not read onboarding progress for %s (ID %d): %s", rec.Name, rec.ID, err)
+		return response
+	}
+	if progress == nil || progress.Step == store.OnboardingDone || progress.Step == store.OnboardingForgotten {
+		return response
+	}
+
+	next, prompt := nextOnboarding(*progress, cmd, cmdArgs)
+	if next.Step != progress.Step {
+		err = onboarding.Advance(ctx, rec.ID, next.Step)
+	} else if next.Reminded && !progress.Reminded {
+		err = onboarding.SetReminded(ctx, rec.ID)
+	}
+	if err != nil {
+		// Better to repeat a prompt next time than to skip one.
+		log.Printf("Could not update onboarding progress for %s (ID %d): %s", rec.Name, rec.ID, err)
+	}
+
+	if prompt == "" {
+		return response
+	}
+	return response + "\n\n" + prompt
+}
+
+// nextOnboarding works out where the user's onboarding goes after they've
+// successfully run the command, and what to prompt them with next.
+func nextOnboarding(progress store.OnboardingProgress, cmd string, cmdArgs []string) (store.OnboardingProgress, string) {
+	if cmd == "unsubscribe" && len(cmdArgs) > 0 {
+		progress.Step = store.OnboardingDone
+		return progress, ""
+	}
+
+	switch progress.Step {
+	case store.OnboardingSubscribe:
+		if cmd == "subscribe" {
+			progress.Step = store.OnboardingSchedule
+			return progress, onboardingSchedulePrompt
+		}
+		if !progress.Reminded {
+			progress.Reminded = true
+			return progress, onboardingSubscribeReminder
+		}
+
+	case store.OnboardingSchedule:
+		// The default schedule is fine too, so interests can come first.
+		switch {
+		case cmd == "schedule" && len(cmdArgs) > 0:
+			progress.Step = store.OnboardingInterests
+			return progress, onboardingInterestsPrompt
+		case setsInterests(cmd, cmdArgs):
+			progress.Step = store.OnboardingDone
+			return progress, onboardingDoneMessage
+		}
+
+	case store.OnboardingInterests:
+		if setsInterests(cmd, cmdArgs) {
+			progress.Step = store.OnboardingDone
+			return progress, onboardingDoneMessage
+		}
+	}
+
+	return progress, ""
+}
+
+// setsInterests reports whether the command saves the user's interests, rather
+// than showing them or being turned down for being too long.
+func setsInterests(cmd string, cmdArgs []string) bool {
+	return cmd == "interests" && len(cmdArgs) > 0 && utf8.RuneCountInString(cmdArgs[0]) <= maxInterestsLength
+}
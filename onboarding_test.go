@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func Test_nextOnboarding(t *testing.T) {
+	at := func(step string) store.OnboardingProgress {
+		return store.OnboardingProgress{ID: 1, Step: step}
+	}
+
+	t.Run("walks through each step", func(t *testing.T) {
+		next, prompt := nextOnboarding(at(store.OnboardingSubscribe), "subscribe", nil)
+		assert.Equal(t, next.Step, store.OnboardingSchedule)
+		assert.Equal(t, prompt, onboardingSchedulePrompt)
+
+		next, prompt = nextOnboarding(next, "schedule", []string{"monday"})
+		assert.Equal(t, next.Step, store.OnboardingInterests)
+		assert.Equal(t, prompt, onboardingInterestsPrompt)
+
+		next, prompt = nextOnboarding(next, "interests", []string{"Rust"})
+		assert.Equal(t, next.Step, store.OnboardingDone)
+		assert.Equal(t, prompt, onboardingDoneMessage)
+	})
+
+	t.Run("reminds to subscribe once", func(t *testing.T) {
+		next, prompt := nextOnboarding(at(store.OnboardingSubscribe), "help", nil)
+		assert.Equal(t, next.Step, store.OnboardingSubscribe)
+		assert.Equal(t, next.Reminded, true)
+		assert.Equal(t, prompt, onboardingSubscribeReminder)
+
+		next, prompt = nextOnboarding(next, "status", nil)
+		assert.Equal(t, next.Step, store.OnboardingSubscribe)
+		assert.Equal(t, prompt, "")
+	})
+
+	t.Run("asking for the days isn't a schedule yet", func(t *testing.T) {
+		next, prompt := nextOnboarding(at(store.OnboardingSchedule), "schedule", nil)
+		assert.Equal(t, next.Step, store.OnboardingSchedule)
+		assert.Equal(t, prompt, "")
+	})
+
+	t.Run("interests can come before a schedule", func(t *testing.T) {
+		next, prompt := nextOnboarding(at(store.OnboardingSchedule), "interests", []string{"Rust"})
+		assert.Equal(t, next.Step, store.OnboardingDone)
+		assert.Equal(t, prompt, onboardingDoneMessage)
+	})
+
+	t.Run("only saved interests count", func(t *testing.T) {
+		next, _ := nextOnboarding(at(store.OnboardingInterests), "interests", nil)
+		assert.Equal(t, next.Step, store.OnboardingInterests)
+
+		tooLong := strings.Repeat("x", maxInterestsLength+1)
+		next, _ = nextOnboarding(at(store.OnboardingInterests), "interests", []string{tooLong})
+		assert.Equal(t, next.Step, store.OnboardingInterests)
+	})
+
+	t.Run("unsubscribing ends it", func(t *testing.T) {
+		// Until they confirm, they've only been asked whether they're sure.
+		next, _ := nextOnboarding(at(store.OnboardingSchedule), "unsubscribe", nil)
+		assert.Equal(t, next.Step, store.OnboardingSchedule)
+
+		next, prompt := nextOnboarding(at(store.OnboardingSchedule), "unsubscribe", []string{"confirm"})
+		assert.Equal(t, next.Step, store.OnboardingDone)
+		assert.Equal(t, prompt, "")
+	})
+}
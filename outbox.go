@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+// maxOutboxAttempts is how many times we'll try to deliver an outbox message
+// before giving up on it.
+const maxOutboxAttempts = 5
+
+// outboxClaimTimeout is how long one delivery attempt can hold a message
+// before another job may try it too. It covers the Zulip client's own retries.
+const outboxClaimTimeout = 5 * time.Minute
+
+// send delivers one outbox message through the Zulip API.
+func (pl *PairingLogic) send(ctx context.Context, msg store.OutboxMessage) error {
+	if msg.Stream != "" {
+		return pl.zulip.PostToTopic(ctx, msg.Stream, msg.Topic, msg.Content)
+	}
+	return pl.zulip.SendUserMessage(ctx, msg.Recipients, msg.Content)
+}
+
+// deliver sends each of the outbox messages and records the outcome, returning
+// the number that could not be delivered.
+//
+// Each message is claimed before it's sent, so that jobs delivering at the same
+// time (like the match job and the outbox retries) don't both send it.
+// Messages that another job has claimed, or that are no longer pending, are
+// skipped and not counted as failures.
+//
+// Delivery is at-least-once: if the process stops after Zulip accepts a
+// message but before it's marked as delivered, it will be sent again once its
+// claim runs out.
+func (pl *PairingLogic) deliver(ctx context.Context, messages []store.OutboxMessage) int {
+	outbox := store.Outbox(pl.db)
+	now := time.Now()
+
+	failed := 0
+	for _, pending := range messages {
+		msg, claimed, err := outbox.Claim(ctx, pending.ID, time.Now().Add(outboxClaimTimeout))
+		if err != nil {
+			log.Printf("Could not claim outbox message %s for delivery: %s", pending.ID, err)
+			failed++
+			continue
+		}
+		if !claimed {
+			continue
+		}
+
+		if msg.Expired(now) {
+			log.Printf("Outbox message %s (%s) expired before delivery to %v", msg.ID, msg.Source, msg.Recipients)
+			if err := outbox.MarkFailed(ctx, msg.ID, "expired"); err != nil {
+				log.Printf("Could not mark outbox message %s as failed: %s", msg.ID, err)
+			}
+			failed++
+			continue
+		}
+
+		sendErr := pl.send(ctx, msg)
+		if sendErr == nil {
+			if err := outbox.MarkDelivered(ctx, msg.ID, time.Now()); err != nil {
+				log.Printf("Could not mark outbox message %s as delivered: %s", msg.ID, err)
+			}
+			continue
+		}
+
+		failed++
+		log.Printf("Error when trying to deliver outbox message %s (%s) to %v: %s", msg.ID, msg.Source, msg.Recipients, sendErr)
+
+		if msg.Attempts+1 >= maxOutboxAttempts {
+			err := outbox.MarkFailed(ctx, msg.ID, sendErr.Error())
+			if err != nil {
+				log.Printf("Could not mark outbox message %s as failed: %s", msg.ID, err)
+			}
+			continue
+		}
+
+		if err := outbox.MarkAttemptFailed(ctx, msg.ID, sendErr); err != nil {
+			log.Printf("Could not record failed attempt for outbox message %s: %s", msg.ID, err)
+		}
+	}
+	return failed
+}
+
+// deliverPendingFrom sends all of the undelivered messages from one source.
+func (pl *PairingLogic) deliverPendingFrom(ctx context.Context, source string) error {
+	pending, err := store.Outbox(pl.db).ListPendingFrom(ctx, source)
+	if err != nil {
+		return fmt.Errorf("list pending outbox messages for %q: %w", source, err)
+	}
+
+	if failed := pl.deliver(ctx, pending); failed > 0 {
+		log.Printf("%d of %d messages for %q could not be delivered yet", failed, len(pending), source)
+	}
+	return nil
+}
+
+// RetryOutbox re-sends any queued messages that haven't been delivered yet,
+// such as those left behind by a job that crashed partway through. It also
+// prunes messages that were settled more than store.OutboxRetention ago.
+func (pl *PairingLogic) RetryOutbox(ctx context.Context) error {
+	outbox := store.Outbox(pl.db)
+
+	pruned, err := outbox.Prune(ctx, time.Now().Add(-store.OutboxRetention))
+	if err != nil {
+		return fmt.Errorf("prune outbox messages: %w", err)
+	}
+	if pruned > 0 {
+		log.Printf("Pruned %d outbox messages older than %s", pruned, store.OutboxRetention)
+	}
+
+	pending, err := outbox.ListPending(ctx)
+	if err != nil {
+		return fmt.Errorf("list pending outbox messages: %w", err)
+	}
+
+	if len(pending) == 0 {
+		return nil
+	}
+
+	failed := pl.deliver(ctx, pending)
+	log.Printf("Retried %d outbox messages: %d delivered, %d failed", len(pending), len(pending)-failed, failed)
+	return nil
+}
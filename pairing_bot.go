@@ -3,8 +3,10 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"math/rand"
 	"net/http"
 	"slices"
@@ -12,6 +14,7 @@ import (
 	"time"
 
 	"cloud.google.com/go/firestore"
+	"github.com/recursecenter/pairing-bot/internal/selection"
 	"github.com/recursecenter/pairing-bot/recurse"
 	"github.com/recursecenter/pairing-bot/store"
 	"github.com/recursecenter/pairing-bot/zulip"
@@ -43,10 +46,16 @@ func maintainersMention() string {
 	return strings.Join(tags, ", ")
 }
 
+// notifyMaintainers sends a group DM to all the maintainers.
+func (pl *PairingLogic) notifyMaintainers(ctx context.Context, message string) error {
+	ids := slices.Sorted(maps.Keys(maintainers))
+	return pl.zulip.SendUserMessage(ctx, ids, message)
+}
+
 type PairingLogic struct {
 	db      *firestore.Client
 	zulip   *zulip.Client
-	recurse *recurse.Client
+	recurse *recurse.Cache
 
 	version         string
 	maintenanceMode bool
@@ -118,13 +127,18 @@ func (pl *PairingLogic) handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// you *should* be able to throw any string at this thing and get back a valid command for dispatch()
-	// if there are no command arguments, cmdArgs will be nil
-	cmd, cmdArgs, err := parseCmd(hook.Data)
-	if err != nil {
-		log.Println(err)
-		// Error cases always correspond to cmd == "help", so it's safe to
-		// continue on to dispatch.
+	// If the bot asked the user a question, this message is probably the
+	// answer. Otherwise, it's a command.
+	cmd, cmdArgs, answered := pl.answer(ctx, user, hook.Data)
+	if !answered {
+		// you *should* be able to throw any string at this thing and get back a valid command for dispatch()
+		// if there are no command arguments, cmdArgs will be nil
+		cmd, cmdArgs, err = parseCmd(hook.Data)
+		if err != nil {
+			log.Println(err)
+			// Error cases always correspond to cmd == "help", so it's safe to
+			// continue on to dispatch.
+		}
 	}
 
 	// the tofu and potatoes right here y'all
@@ -132,6 +146,8 @@ func (pl *PairingLogic) handle(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Println(err)
 		// Errors come with non-empty messages sometimes, so continue on.
+	} else {
+		response = pl.continueOnboarding(ctx, user, cmd, cmdArgs, response)
 	}
 
 	if err = responder.Encode(zulip.Reply(response)); err != nil {
@@ -141,26 +157,29 @@ func (pl *PairingLogic) handle(w http.ResponseWriter, r *http.Request) {
 }
 
 // Match generates new pairs for today and sends notifications for them.
+//
+// The notifications are written to the outbox before any of them are sent, so
+// a run that fails partway through can be repeated: a second run for the same
+// day finishes delivering the first run's matches instead of making new ones.
 func (pl *PairingLogic) Match(ctx context.Context) error {
+	now := time.Now()
+	source := "match " + now.UTC().Format(time.DateOnly)
+
 	recursersList, err := store.Recursers(pl.db).ListPairingTomorrow(ctx)
 	log.Println(recursersList)
 	if err != nil {
 		return fmt.Errorf("get today's recursers from DB: %w", err)
 	}
 
+	// Leave out anyone we can't message before pairing, so that their would-be
+	// partners get matched with someone else instead.
+	recursersList = pl.messageable(ctx, recursersList)
+
 	skippersList, err := store.Recursers(pl.db).ListSkippingTomorrow(ctx)
 	if err != nil {
 		return fmt.Errorf("get today's skippers from DB: %w", err)
 	}
 
-	// get everyone who was set to skip today and set them back to isSkippingTomorrow = false
-	for _, skipper := range skippersList {
-		err := store.Recursers(pl.db).UnsetSkippingTomorrow(ctx, &skipper)
-		if err != nil {
-			log.Printf("Could not unset skipping for recurser %v: %s\n", skipper.ID, err)
-		}
-	}
-
 	// Reproducible randomness:
 	// - Get and log a random seed
 	// - Run the shuffle using a source derived from that seed
@@ -168,61 +187,420 @@ func (pl *PairingLogic) Match(ctx context.Context) error {
 	// In dev, you should be able to set the seed below to get the same shuffle.
 	seed := rand.Int63()
 	log.Printf("Shuffling %d Recursers using random seed: %d", len(recursersList), seed)
-	randSrc := rand.NewSource(seed)
-	// shuffle our recursers. This will not error if the list is empty
-	rand.New(randSrc).Shuffle(len(recursersList), func(i, j int) { recursersList[i], recursersList[j] = recursersList[j], recursersList[i] })
+	selection.Shuffle(recursersList, seed)
 
-	// if for some reason there's no matches today, we're done
-	if len(recursersList) == 0 {
-		log.Println("No one was signed up to pair today -- so there were no matches")
-		return nil
-	}
+	// Nobody should hear about today's matches after today is over.
+	expiresAt := now.Add(20 * time.Hour).Unix()
+
+	var messages []store.OutboxMessage
 
-	// message the peeps!
+	pairs, unmatched := selection.PairUp(recursersList)
 
-	// if there's an odd number today, message the last person in the list
-	// and tell them they don't get a match today, then knock them off the list
-	if len(recursersList)%2 != 0 {
-		recurser := recursersList[len(recursersList)-1]
-		recursersList = recursersList[:len(recursersList)-1]
+	// message anyone we couldn't find a partner for and tell them they don't
+	// get a match today. There's one if there's an odd number today, and maybe
+	// more if partner preferences rule out the rest.
+	for _, recurser := range unmatched {
 		log.Printf("%s was the odd-one-out today", recurser.Name)
 
-		err := pl.zulip.SendUserMessage(ctx, []int64{recurser.ID}, oddOneOutMessage)
-		if err != nil {
-			log.Printf("Error when trying to send oddOneOut message to %s: %s\n", recurser.Name, err)
+		messages = append(messages, store.OutboxMessage{
+			Recipients: []int64{recurser.ID},
+			Content:    oddOneOutMessage,
+			ExpiresAt:  expiresAt,
+			CreatedAt:  now.Unix(),
+		})
+	}
+
+	for _, pair := range pairs {
+		rc1, rc2 := pair[0], pair[1]
+
+		messages = append(messages, store.OutboxMessage{
+			Recipients: []int64{rc1.ID, rc2.ID},
+			Content:    withInterests(matchedMessage, pair),
+			ExpiresAt:  expiresAt,
+			CreatedAt:  now.Unix(),
+		})
+		log.Println(rc1.Name, "was", "matched", "with", rc2.Name)
+	}
+
+	// Commit to today's matches before telling anyone about them. If another
+	// run beat us to it, defer to that one.
+	queued, err := store.Outbox(pl.db).Enqueue(ctx, source, messages)
+	if err != nil {
+		return fmt.Errorf("queue today's match messages: %w", err)
+	}
+
+	if queued {
+		// message the peeps!
+		if err := pl.deliverPendingFrom(ctx, source); err != nil {
+			return err
+		}
+		pl.repairMatches(ctx, source, recursersList, unmatched, expiresAt)
+	} else {
+		log.Printf("Matches for %q were already queued by an earlier run; delivering any leftovers instead of matching again", source)
+		if err := pl.deliverPendingFrom(ctx, source); err != nil {
+			return err
 		}
 	}
 
-	for i := 0; i < len(recursersList); i += 2 {
-		rc1 := recursersList[i]
-		rc2 := recursersList[i+1]
-		ids := []int64{rc1.ID, rc2.ID}
+	// The earlier run may have stopped before finishing up, so these steps
+	// are repeated on every run. They're based on the queued matches rather
+	// than this run's, which are thrown away if they weren't queued.
+	return pl.finishMatch(ctx, source, recursersList, skippersList, now)
+}
 
-		err := pl.zulip.SendUserMessage(ctx, ids, matchedMessage)
+// finishMatch clears the skips that the source's matches used up and records
+// how many pairs were made. It's safe to repeat: skips requested after the
+// matches were queued are kept, and the day's pairing record is overwritten
+// with the same totals.
+func (pl *PairingLogic) finishMatch(ctx context.Context, source string, recursers, skippers []store.Recurser, now time.Time) error {
+	var messages []store.OutboxMessage
+	for _, src := range []string{source, repairSource(source)} {
+		fromSource, err := store.Outbox(pl.db).ListFrom(ctx, src)
 		if err != nil {
-			log.Printf("Error when trying to send matchedMessage to %s and %s: %s\n", rc1.Name, rc2.Name, err)
+			return fmt.Errorf("list match messages for %q: %w", src, err)
 		}
-		log.Println(rc1.Name, "was", "matched", "with", rc2.Name)
+		messages = append(messages, fromSource...)
 	}
 
-	numRecursersPairedUp := len(recursersList)
+	// When the matches were queued by an earlier run, skips up until then were
+	// for those matches.
+	matchedAt := now
+	for _, msg := range messages {
+		if queuedAt := time.Unix(msg.CreatedAt, 0); queuedAt.Before(matchedAt) {
+			matchedAt = queuedAt
+		}
+	}
 
-	log.Printf("Pairing Bot paired up %d recursers today", numRecursersPairedUp)
+	// get everyone who was set to skip today and set them back to isSkippingTomorrow = false,
+	// unless they asked to skip again while we were matching
+	for _, skipper := range skippers {
+		err := store.Recursers(pl.db).UnsetSkippingTomorrow(ctx, skipper.ID, matchedAt)
+		if err != nil {
+			log.Printf("Could not unset skipping for recurser %v: %s\n", skipper.ID, err)
+		}
+	}
 
-	pairing := store.Pairing{
-		Value:     numRecursersPairedUp / 2,
-		Timestamp: time.Now().Unix(),
+	// if for some reason there's no matches today, we're done
+	if len(messages) == 0 {
+		log.Println("No one was signed up to pair today -- so there were no matches")
+		return nil
 	}
 
+	pairing := countPairs(messages, recursers)
+	pairing.Timestamp = matchedAt.Unix()
+
+	log.Printf("Pairing Bot paired up %d recursers today", 2*pairing.Value)
+
 	if err := store.Pairings(pl.db).SetNumPairings(ctx, pairing); err != nil {
 		log.Printf("Failed to record today's pairings: %s", err)
 	}
+	return nil
+}
+
+// countPairs totals up the pairs introduced by the match messages, including
+// re-pairs, and how many of them included an alum. Messages that failed
+// don't count, since those pairs never met: either one of them couldn't be
+// reached (and the other was re-paired), or the message expired. The
+// Timestamp is left unset.
+func countPairs(messages []store.OutboxMessage, recursers []store.Recurser) store.Pairing {
+	byID := make(map[int64]store.Recurser)
+	for _, rec := range recursers {
+		byID[rec.ID] = rec
+	}
+
+	var pairing store.Pairing
+	for _, msg := range messages {
+		if len(msg.Recipients) != 2 || msg.Status == store.OutboxFailed {
+			continue
+		}
+		pairing.Value++
+
+		for _, id := range msg.Recipients {
+			if rec, ok := byID[id]; ok && rec.IsAlum() {
+				pairing.Alumni++
+				break
+			}
+		}
+	}
+	return pairing
+}
+
+// repairMatches deals with match messages from the source that couldn't be
+// delivered. After one more try, it looks at each failed pair: if only one
+// person's Zulip account is the problem, the other is re-matched with someone
+// else whose partner couldn't be reached, or with today's odd-one-out. Any
+// failures left over are reported to the maintainers. They stay in the outbox
+// to be retried until they expire.
+//
+// recursers are everyone who was matched, and unmatched are the odd-ones-out.
+func (pl *PairingLogic) repairMatches(ctx context.Context, source string, recursers, unmatched []store.Recurser, expiresAt int64) {
+	outbox := store.Outbox(pl.db)
+
+	// The first failures may have been temporary.
+	if err := pl.deliverPendingFrom(ctx, source); err != nil {
+		log.Printf("Could not retry match messages: %s", err)
+		return
+	}
+
+	failed, err := outbox.ListPendingFrom(ctx, source)
+	if err != nil {
+		log.Printf("Could not list undelivered match messages: %s", err)
+		return
+	}
+
+	// Messages that the outbox retries are delivering right now haven't
+	// failed (yet).
+	now := time.Now()
+	failed = slices.DeleteFunc(failed, func(msg store.OutboxMessage) bool { return msg.Claimed(now) })
+	if len(failed) == 0 {
+		return
+	}
+
+	byID := make(map[int64]store.Recurser)
+	for _, rec := range recursers {
+		byID[rec.ID] = rec
+	}
+
+	var unresolved []store.OutboxMessage
+	var leftovers []store.Recurser
+messages:
+	for i, msg := range failed {
+		if len(msg.Recipients) != 2 {
+			unresolved = append(unresolved, msg)
+			continue
+		}
+
+		var healthy, broken []int64
+		for _, id := range msg.Recipients {
+			ok, err := pl.canMessageUser(ctx, id)
+			if err != nil {
+				// Whatever's wrong with Zulip is likely wrong for everyone,
+				// so leave the rest for the outbox to retry.
+				log.Printf("Could not check Zulip account %d: %s", id, err)
+				unresolved = append(unresolved, failed[i:]...)
+				break messages
+			}
+			if ok {
+				healthy = append(healthy, id)
+			} else {
+				broken = append(broken, id)
+			}
+		}
+
+		// Only re-pair when we know which person is the problem.
+		if len(healthy) != 1 || len(broken) != 1 {
+			unresolved = append(unresolved, msg)
+			continue
+		}
+
+		rec, ok := byID[healthy[0]]
+		if !ok {
+			unresolved = append(unresolved, msg)
+			continue
+		}
+
+		log.Printf("Re-pairing %s (ID %d), whose partner %d can't be messaged", rec.Name, rec.ID, broken[0])
+		if err := store.Recursers(pl.db).SetDeactivated(ctx, broken[0], true); err != nil {
+			log.Printf("Could not flag %d as deactivated: %s", broken[0], err)
+		}
+		if err := outbox.MarkFailed(ctx, msg.ID, fmt.Sprintf("re-paired: %d can't be messaged", broken[0])); err != nil {
+			log.Printf("Could not mark outbox message %s as failed: %s", msg.ID, err)
+		}
+
+		leftovers = append(leftovers, rec)
+	}
+
+	if len(leftovers) > 0 {
+		unresolved = append(unresolved, pl.rematch(ctx, source, leftovers, unmatched, expiresAt)...)
+	}
+
+	if len(unresolved) == 0 {
+		return
+	}
+
+	report := fmt.Sprintf("%d match %s for %q couldn't be delivered. I'll keep retrying until they expire:\n", len(unresolved), plural(len(unresolved), "message", "messages"), source)
+	for _, msg := range unresolved {
+		report += fmt.Sprintf("* `%s` to %v: %s\n", msg.ID, msg.Recipients, msg.LastError)
+	}
+	if err := pl.notifyMaintainers(ctx, report); err != nil {
+		log.Printf("Could not tell the maintainers about undelivered match messages: %s", err)
+	}
+}
+
+// withInterests adds what each of the pair would like to pair on, if they've
+// said, to their match message.
+func withInterests(message string, pair [2]store.Recurser) string {
+	for _, rec := range pair {
+		if rec.Interests != "" {
+			message += fmt.Sprintf("\n\n@_**%s|%d** would like to pair on: %s", rec.Name, rec.ID, rec.Interests)
+		}
+	}
+	return message
+}
+
+// rematch pairs up the leftovers, whose partners couldn't be reached, with
+// each other or with today's odd-ones-out. It returns any of the new messages
+// that couldn't be delivered either.
+func (pl *PairingLogic) rematch(ctx context.Context, source string, leftovers, unmatched []store.Recurser, expiresAt int64) []store.OutboxMessage {
+	pairs, alone := selection.PairUp(append(slices.Clone(leftovers), unmatched...))
+
+	var messages []store.OutboxMessage
+	for _, pair := range pairs {
+		messages = append(messages, store.OutboxMessage{
+			Recipients: []int64{pair[0].ID, pair[1].ID},
+			Content:    withInterests(rematchedMessage, pair),
+			ExpiresAt:  expiresAt,
+		})
+		log.Println(pair[0].Name, "was", "re-matched", "with", pair[1].Name)
+	}
+
+	// The odd-ones-out have already heard that they won't be matched.
+	for _, rec := range alone {
+		if slices.ContainsFunc(leftovers, func(r store.Recurser) bool { return r.ID == rec.ID }) {
+			messages = append(messages, store.OutboxMessage{
+				Recipients: []int64{rec.ID},
+				Content:    partnerUnreachableMessage,
+				ExpiresAt:  expiresAt,
+			})
+		}
+	}
+
+	rematchSource := repairSource(source)
+	if _, err := store.Outbox(pl.db).Enqueue(ctx, rematchSource, messages); err != nil {
+		log.Printf("Could not queue re-pairing messages: %s", err)
+		return nil
+	}
+	if err := pl.deliverPendingFrom(ctx, rematchSource); err != nil {
+		log.Printf("Could not deliver re-pairing messages: %s", err)
+		return nil
+	}
+
+	failed, err := store.Outbox(pl.db).ListPendingFrom(ctx, rematchSource)
+	if err != nil {
+		log.Printf("Could not list undelivered re-pairing messages: %s", err)
+		return nil
+	}
+	return failed
+}
+
+// repairSource is the outbox source for the re-pairs made after the match
+// messages from source couldn't be delivered.
+func repairSource(source string) string {
+	return source + " re-pair"
+}
+
+// canMessageUser looks up whether the user's Zulip account can be messaged.
+// Accounts that Zulip doesn't know about can't be. Any other error is
+// returned, since it doesn't say anything about the account: an expired API
+// key shouldn't make everyone look deactivated.
+func (pl *PairingLogic) canMessageUser(ctx context.Context, userID int64) (bool, error) {
+	account, err := pl.zulip.GetUser(ctx, userID)
+	if zulip.IsNoSuchUser(err) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return selection.CanMessage(account, true), nil
+}
+
+// messageable returns the recursers whose Zulip accounts can be messaged. The
+// rest are flagged as deactivated, so they won't be matched again until the
+// sync job sees that their accounts are active.
+//
+// If Zulip can't tell us who's active, everyone is kept, since we're more
+// likely to be able to message them than not.
+func (pl *PairingLogic) messageable(ctx context.Context, recursers []store.Recurser) []store.Recurser {
+	members, err := pl.zulip.ListUsers(ctx)
+	if err != nil {
+		log.Printf("Could not check for deactivated Zulip accounts: %s", err)
+		return recursers
+	}
+
+	accounts := make(map[int64]zulip.Account, len(members))
+	for _, a := range members {
+		accounts[a.UserID] = a
+	}
+
+	valid, invalid := selection.Messageable(recursers, accounts)
+	for _, rec := range invalid {
+		log.Printf("Not matching %s (ID %d), whose Zulip account is deactivated or a bot", rec.Name, rec.ID)
+
+		if err := store.Recursers(pl.db).SetDeactivated(ctx, rec.ID, true); err != nil {
+			log.Printf("Could not flag %s (ID %d) as deactivated: %s", rec.Name, rec.ID, err)
+		}
+	}
+	return valid
+}
+
+// WarnOffboarding tells subscribers whose batch is about to end that they'll
+// be unsubscribed, so that anyone who wants to keep pairing can reply `stay`
+// first. Each batch's warnings are only queued once, no matter how many times
+// this runs.
+func (pl *PairingLogic) WarnOffboarding(ctx context.Context) error {
+	now := time.Now()
+
+	batches, err := pl.recurse.AllBatches(ctx)
+	if err != nil {
+		return fmt.Errorf("get list of batches: %w", err)
+	}
+
+	subscribers, err := store.Recursers(pl.db).GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("get subscribers: %w", err)
+	}
+
+	for _, batch := range selection.EndingBatches(batches, now) {
+		profiles, err := pl.recurse.BatchRecursers(ctx, batch.ID)
+		if err != nil {
+			return fmt.Errorf("get recursers in %s: %w", batch.Name, err)
+		}
+
+		lastDay := time.Time(batch.EndDate)
+		content := fmt.Sprintf(offboardWarningMessage, batch.Name, lastDay.Format("Monday, January 2"))
+
+		// People staying on for another batch (or working at RC) won't be
+		// offboarded yet.
+		warn, _ := selection.OffboardWarnings(batch, profiles, subscribers)
+
+		var messages []store.OutboxMessage
+		for _, rec := range warn {
+			messages = append(messages, store.OutboxMessage{
+				Recipients: []int64{rec.ID},
+				Content:    content,
+				// There's no point warning anyone after the offboarding.
+				ExpiresAt: lastDay.AddDate(0, 0, 1).Unix(),
+			})
+		}
+
+		if len(messages) == 0 {
+			continue
+		}
+
+		source := fmt.Sprintf("offboard warning %d", batch.ID)
+		queued, err := store.Outbox(pl.db).Enqueue(ctx, source, messages)
+		if err != nil {
+			return fmt.Errorf("queue offboarding warnings for %s: %w", batch.Name, err)
+		}
+		if queued {
+			log.Printf("Warning %d subscribers that %s is ending", len(messages), batch.Name)
+		}
+
+		if err := pl.deliverPendingFrom(ctx, source); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
 
-// EndOfBatch unsubscribes everyone who just never-graduated with this batch.
+// EndOfBatch unsubscribes everyone who just never-graduated with this batch,
+// except for the alumni who asked to stay.
 func (pl *PairingLogic) EndOfBatch(ctx context.Context) error {
+	// Profile data only changes at the end of each batch, so make sure we
+	// don't compare against last week's.
+	pl.recurse.Invalidate()
+
 	// getting all the recursers
 	recursersList, err := store.Recursers(pl.db).GetAllUsers(ctx)
 	if err != nil {
@@ -234,55 +612,103 @@ func (pl *PairingLogic) EndOfBatch(ctx context.Context) error {
 		return fmt.Errorf("get active Recursers: %w", err)
 	}
 
-	var idsOfPeopleAtRc []int64
-	for _, p := range profiles {
-		idsOfPeopleAtRc = append(idsOfPeopleAtRc, p.ZulipID)
+	atRC := selection.AtRC(profiles)
+
+	for _, recurser := range recursersList {
+		log.Printf("User: %s was at RC last week: %t and is at RC this week: %t", recurser.Name, recurser.CurrentlyAtRC, atRC[recurser.ID])
+
+		// Only touch this one field so that we don't undo any changes the
+		// user made since we loaded the list.
+		if err = store.Recursers(pl.db).SetCurrentlyAtRC(ctx, recurser.ID, atRC[recurser.ID]); err != nil {
+			log.Printf("Error encountered while update currentlyAtRC status for user: %s (ID %d)", recurser.Name, recurser.ID)
+		}
+	}
+
+	// If they were at RC last week but not this week then we assume they have graduated or otherwise left RC
+	// In that case we unsubscribe them from pairing bot so that inactive people do not get matched
+	// If people who have left RC still want to use pairing bot, we give them the option to resubscribe
+	offboard, keep := selection.Leavers(recursersList, atRC)
+	for _, recurser := range keep {
+		log.Printf("Keeping %s (ID %d) subscribed as an alum", recurser.Name, recurser.ID)
 	}
 
-	for i := 0; i < len(recursersList); i++ {
+	for _, recurser := range offboard {
+		var message string
+
+		err = store.Recursers(pl.db).Unsubscribe(ctx, recurser.ID)
+		if err != nil {
+			log.Println(err)
+			message = fmt.Sprintf("Uh oh, I was trying to offboard you since it's the end of batch, but something went wrong. Consider messaging the maintainers to let them know this happened: %s", maintainersMention())
+		} else {
+			log.Printf("This user has been unsubscribed from pairing bot: %s (ID: %d)", recurser.Name, recurser.ID)
+
+			message = offboardedMessage
+		}
 
-		recurser := &recursersList[i]
+		err := pl.zulip.SendUserMessage(ctx, []int64{recurser.ID}, message)
+		if err != nil {
+			log.Printf("Error when trying to send offboarding message to %s (ID %d): %s", recurser.Name, recurser.ID, err)
+		}
+	}
 
-		isAtRCThisWeek := slices.Contains(idsOfPeopleAtRc, recurser.ID)
-		wasAtRCLastWeek := recursersList[i].CurrentlyAtRC
+	return nil
+}
 
-		log.Printf("User: %s was at RC last week: %t and is at RC this week: %t", recurser.Name, wasAtRCLastWeek, isAtRCThisWeek)
+// Sync refreshes the names, emails, and batches of subscribers from Zulip and
+// the Recurse API. Otherwise, they're only refreshed when someone DMs the bot.
+// It also marks subscribers whose Zulip accounts have been deactivated, so
+// that they stop being matched, and unmarks them if they're reactivated.
+func (pl *PairingLogic) Sync(ctx context.Context) error {
+	recursersList, err := store.Recursers(pl.db).GetAllUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("get subscribers: %w", err)
+	}
 
-		recurser.CurrentlyAtRC = isAtRCThisWeek
+	members, err := pl.zulip.ListUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("list Zulip users: %w", err)
+	}
 
-		if err = store.Recursers(pl.db).Set(ctx, recurser.ID, recurser); err != nil {
-			log.Printf("Error encountered while update currentlyAtRC status for user: %s (ID %d)", recurser.Name, recurser.ID)
+	accounts := make(map[int64]zulip.Account, len(members))
+	for _, a := range members {
+		accounts[a.UserID] = a
+	}
+
+	now := time.Now()
+	updated := 0
+	for _, rec := range recursersList {
+		profile, atRC, err := pl.recurse.ActiveProfile(ctx, rec.ID)
+		if err != nil {
+			return fmt.Errorf("get Recurse profile: %w", err)
 		}
 
-		// If they were at RC last week but not this week then we assume they have graduated or otherwise left RC
-		// In that case we remove them from pairing bot so that inactive people do not get matched
-		// If people who have left RC still want to use pairing bot, we give them the option to resubscribe
-		if wasAtRCLastWeek && !isAtRCThisWeek {
-			var message string
+		account, ok := accounts[rec.ID]
+		fields := selection.Synced(rec, account, ok, profile, atRC, now)
 
-			err = store.Recursers(pl.db).Delete(ctx, recurser.ID)
-			if err != nil {
-				log.Println(err)
-				message = fmt.Sprintf("Uh oh, I was trying to offboard you since it's the end of batch, but something went wrong. Consider messaging the maintainers to let them know this happened: %s", maintainersMention())
-			} else {
-				log.Printf("This user has been unsubscribed from pairing bot: %s (ID: %d)", recurser.Name, recurser.ID)
+		if fields == rec.Synced() {
+			continue
+		}
 
-				message = offboardedMessage
-			}
+		if fields.Deactivated && !rec.Deactivated {
+			log.Printf("%s (ID %d) has no active Zulip account, so they won't be matched", rec.Name, rec.ID)
+		} else if !fields.Deactivated && rec.Deactivated {
+			log.Printf("%s (ID %d) has an active Zulip account again", rec.Name, rec.ID)
+		}
 
-			err := pl.zulip.SendUserMessage(ctx, []int64{recurser.ID}, message)
-			if err != nil {
-				log.Printf("Error when trying to send offboarding message to %s (ID %d): %s", recurser.Name, recurser.ID, err)
-			}
+		if err := store.Recursers(pl.db).Sync(ctx, rec.ID, fields); err != nil {
+			log.Printf("Could not sync %s (ID %d): %s", rec.Name, rec.ID, err)
+			continue
 		}
+		updated++
 	}
 
+	log.Printf("Synced %d of %d subscribers", updated, len(recursersList))
 	return nil
 }
 
 // Checkin posts a message to Pairing Bot's checkin topic.
 func (pl *PairingLogic) Checkin(ctx context.Context) error {
-	numPairings, err := store.Pairings(pl.db).GetTotalPairingsDuringLastWeek(ctx)
+	pairings, err := store.Pairings(pl.db).GetTotalsDuringLastWeek(ctx)
 	if err != nil {
 		log.Println("Unable to get the total number of pairings during the last week: : ", err)
 	}
@@ -293,11 +719,25 @@ func (pl *PairingLogic) Checkin(ctx context.Context) error {
 	}
 
 	review, err := store.Reviews(pl.db).GetRandom(ctx)
-	if err != nil {
+	if err != nil && !errors.Is(err, store.ErrNoReviews) {
 		log.Println("Could not get a random review from DB: ", err)
 	}
 
-	checkinMessage, err := renderCheckin(time.Now(), numPairings, len(recursersList), review.Content)
+	alumni := 0
+	for _, rec := range recursersList {
+		if rec.IsAlum() {
+			alumni++
+		}
+	}
+
+	checkinMessage, err := renderCheckin(checkinStats{
+		Now:            time.Now(),
+		Recursers:      len(recursersList),
+		Alumni:         alumni,
+		Pairings:       pairings.Value,
+		AlumniPairings: pairings.Alumni,
+		Review:         review.Content,
+	})
 	if err != nil {
 		return fmt.Errorf("render checkin: %w", err)
 	}
@@ -309,40 +749,51 @@ func (pl *PairingLogic) Checkin(ctx context.Context) error {
 	return nil
 }
 
-// Welcome sends a "Welcome to Pairing Bot" message to introduce the new batch
+// Welcome sends a "Welcome to Pairing Bot" message to introduce each new batch
 // to Pairing Bot.
 //
 // We send this message during the second week of batch. The first week is a
 // bit overwhelming with all of the orientation meetings and messages, and
-// people haven't had time to think too much about their projects.
+// people haven't had time to think too much about their projects. Mini batches
+// are only 1 week long, so they get a shorter welcome during that week
+// instead.
+//
+// Each batch's welcome is queued in the outbox under its own source, so a
+// batch is only ever welcomed once, no matter how many times this runs.
 func (pl *PairingLogic) Welcome(ctx context.Context) error {
 	batches, err := pl.recurse.AllBatches(ctx)
 	if err != nil {
 		return fmt.Errorf("get list of batches: %w", err)
 	}
 
-	// Loop through the batches until we find the first non-mini batch. Mini
-	// batches are only 1 week long, so it doesn't make sense to send a message
-	// 1 week after a mini batch has started :joy:
-	var currentBatch recurse.Batch
-	for _, batch := range batches {
-		if batch.IsMini() {
-			continue
+	now := time.Now()
+	for _, w := range selection.Welcomes(batches, now) {
+		var msg string
+		if w.Mini {
+			msg, err = renderMiniWelcome(w.Batch.Name)
+		} else {
+			msg, err = renderWelcome(now)
+		}
+		if err != nil {
+			return fmt.Errorf("render welcome message for %s: %w", w.Batch.Name, err)
 		}
 
-		currentBatch = batch
-		break
-	}
-
-	now := time.Now()
-	if currentBatch.IsSecondWeek(now) {
-		msg, err := renderWelcome(now)
+		source := fmt.Sprintf("welcome %d", w.Batch.ID)
+		queued, err := store.Outbox(pl.db).Enqueue(ctx, source, []store.OutboxMessage{{
+			Stream:    pl.welcomeStream,
+			Topic:     "🍐🤖",
+			Content:   msg,
+			ExpiresAt: w.ExpiresAt.Unix(),
+		}})
 		if err != nil {
-			return fmt.Errorf("render welcome message: %w", err)
+			return fmt.Errorf("queue welcome message for %s: %w", w.Batch.Name, err)
+		}
+		if !queued {
+			log.Printf("%s was already welcomed", w.Batch.Name)
 		}
 
-		if err := pl.zulip.PostToTopic(ctx, pl.welcomeStream, "🍐🤖", msg); err != nil {
-			return fmt.Errorf("send welcome message: %w", err)
+		if err := pl.deliverPendingFrom(ctx, source); err != nil {
+			return err
 		}
 	}
 
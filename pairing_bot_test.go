@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/store"
+	"github.com/recursecenter/pairing-bot/zulip"
+)
+
+func Test_countPairs(t *testing.T) {
+	recursers := []store.Recurser{
+		{ID: 1, CurrentlyAtRC: true},
+		{ID: 2, CurrentlyAtRC: true},
+		{ID: 3, CurrentlyAtRC: true},
+		{ID: 4, Alumni: true},
+		{ID: 5, Alumni: true},
+		{ID: 6, Alumni: true},
+	}
+
+	pairing := countPairs([]store.OutboxMessage{
+		{Recipients: []int64{1, 2}, Status: store.OutboxDelivered},
+		{Recipients: []int64{3, 4}, Status: store.OutboxDelivered},
+		{Recipients: []int64{5, 8}, Status: store.OutboxFailed, LastError: "re-paired: 8 can't be messaged"},
+		// Odd-ones-out aren't pairs.
+		{Recipients: []int64{7}, Status: store.OutboxDelivered},
+
+		// 5 was re-paired with the odd-one-out.
+		{Source: repairSource("match"), Recipients: []int64{5, 7}, Status: store.OutboxDelivered},
+		// Pairs that may still be delivered count.
+		{Recipients: []int64{6, 9}, Status: store.OutboxPending},
+	}, recursers)
+
+	assert.Equal(t, pairing.Value, 4)
+	assert.Equal(t, pairing.Alumni, 3)
+}
+
+func Test_canMessageUser(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/users/1":
+			w.Write([]byte(`{"result": "success", "user": {"user_id": 1, "is_active": true}}`))
+		case "/users/2":
+			w.Write([]byte(`{"result": "success", "user": {"user_id": 2, "is_active": false}}`))
+		case "/users/3":
+			w.Write([]byte(`{"result": "success", "user": {"user_id": 3, "is_active": true, "is_bot": true}}`))
+		case "/users/4":
+			http.Error(w, `{"result": "error", "msg": "No such user", "code": "BAD_REQUEST"}`, http.StatusBadRequest)
+		case "/users/5":
+			http.Error(w, `{"result": "error", "msg": "Not found"}`, http.StatusNotFound)
+		case "/users/6":
+			http.Error(w, `{"result": "error", "msg": "Invalid API key", "code": "INVALID_API_KEY"}`, http.StatusUnauthorized)
+		case "/users/7":
+			http.Error(w, `{"result": "error", "msg": "Insufficient permission", "code": "BAD_REQUEST"}`, http.StatusForbidden)
+		default:
+			http.Error(w, "oops!", http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	client, err := zulip.NewClient(
+		zulip.StaticCredentials("bot@recurse.example.net", "fake-api-key"),
+		zulip.WithHTTP(srv.Client()),
+		zulip.WithBaseURL(srv.URL),
+		zulip.WithMaxAttempts(1),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pl := &PairingLogic{zulip: client}
+
+	ctx := context.Background()
+	for id, expected := range map[int64]bool{1: true, 2: false, 3: false, 4: false, 5: false} {
+		ok, err := pl.canMessageUser(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, ok, expected)
+	}
+
+	// Auth and server problems don't tell us anything about the account.
+	for _, id := range []int64{6, 7, 8} {
+		if _, err := pl.canMessageUser(ctx, id); err == nil {
+			t.Errorf("expected an error for user %d", id)
+		}
+	}
+}
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
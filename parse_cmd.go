@@ -6,6 +6,9 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/store"
 )
 
 var ErrUnknownCommand = errors.New("unknown command")
@@ -21,12 +24,22 @@ func parseCmd(cmdStr string) (string, []string, error) {
 	rest = strings.TrimSpace(rest)
 
 	switch name {
-	case "subscribe", "unsubscribe", "help", "status", "cookie":
+	case "subscribe", "stay", "my-data", "my-reviews", "help", "status", "cookie":
 		if len(rest) > 0 {
 			return "help", nil, fmt.Errorf("%w: wanted no arguments", ErrInvalidArguments)
 		}
 		return name, nil, nil
 
+	case "unsubscribe", "forget-me":
+		switch strings.ToLower(rest) {
+		case "":
+			return name, nil, nil
+		case "confirm":
+			return name, []string{"confirm"}, nil
+		default:
+			return "help", nil, fmt.Errorf(`%w: wanted nothing or "confirm"`, ErrInvalidArguments)
+		}
+
 	case "version":
 		// Ignore any extra arguments.
 		return name, nil, nil
@@ -37,25 +50,37 @@ func parseCmd(cmdStr string) (string, []string, error) {
 		}
 		return name, []string{rest}, nil
 
-	case "get-reviews":
+	case "edit-review":
+		// Review IDs are case-sensitive, so they aren't lowercased.
+		id, content, _ := strings.Cut(rest, " ")
+		content = strings.TrimSpace(content)
+		if id == "" || content == "" {
+			return "help", nil, fmt.Errorf(`%w: wanted review ID and new content`, ErrInvalidArguments)
+		}
+		return name, []string{id, content}, nil
+
+	case "delete-review":
 		args := strings.Fields(rest)
-		switch len(args) {
-		case 0:
+		if len(args) != 1 {
+			return "help", nil, fmt.Errorf(`%w: wanted one review ID`, ErrInvalidArguments)
+		}
+		return name, args, nil
+
+	case "get-reviews":
+		args := strings.Fields(strings.ToLower(rest))
+		if _, err := parseReviewsArgs(args); err != nil {
+			return "help", nil, fmt.Errorf("%w: %w", ErrInvalidArguments, err)
+		}
+		if len(args) == 0 {
 			return name, nil, nil
-		case 1:
-			n, err := strconv.Atoi(args[0])
-			if err != nil || n < 0 {
-				return "help", nil, fmt.Errorf(`%w: wanted a positive integer`, ErrInvalidArguments)
-			}
-			return name, args, nil
-		default:
-			return "help", nil, fmt.Errorf(`%w: wanted a positive integer`, ErrInvalidArguments)
 		}
+		return name, args, nil
 
 	case "schedule":
 		args := strings.Fields(rest)
 		if len(args) == 0 {
-			return "help", nil, fmt.Errorf("%w: wanted list of days", ErrInvalidArguments)
+			// The user will be asked which days they'd like.
+			return name, nil, nil
 		}
 
 		var userSchedule []string
@@ -71,6 +96,21 @@ func parseCmd(cmdStr string) (string, []string, error) {
 
 		return "schedule", userSchedule, nil
 
+	case "partners":
+		switch pref := strings.ToLower(rest); pref {
+		case store.PartnersAnyone, store.PartnersAlumniOnly, store.PartnersCurrentOnly:
+			return name, []string{pref}, nil
+		default:
+			return "help", nil, fmt.Errorf(`%w: wanted "anyone", "alumni-only", or "current-only"`, ErrInvalidArguments)
+		}
+
+	case "interests":
+		// Keep the user's own wording (and capitalization) for their partners.
+		if rest == "" {
+			return name, nil, nil
+		}
+		return name, []string{rest}, nil
+
 	case "skip", "unskip":
 		// TODO(#49): Allow (un)skipping days other than tomorrow
 		if rest != "tomorrow" {
@@ -79,11 +119,39 @@ func parseCmd(cmdStr string) (string, []string, error) {
 		return name, []string{"tomorrow"}, nil
 	case "thank", "thanks":
 		return "thanks", nil, nil
+
+	case "admin":
+		return parseAdminCmd(rest)
+
 	default:
 		return "help", nil, fmt.Errorf("%w: %q", ErrUnknownCommand, name)
 	}
 }
 
+// parseAdminCmd parses the maintainer-only subcommands of "admin". Whether the
+// sender is allowed to use them is checked later, in dispatch.
+func parseAdminCmd(rest string) (string, []string, error) {
+	args := strings.Fields(rest)
+	if len(args) == 0 {
+		return "help", nil, fmt.Errorf("%w: wanted admin subcommand", ErrInvalidArguments)
+	}
+
+	sub := strings.ToLower(args[0])
+	switch sub {
+	case "jobs", "schema", "refresh":
+		if len(args) > 1 {
+			return "help", nil, fmt.Errorf("%w: wanted no arguments", ErrInvalidArguments)
+		}
+		return "admin", []string{sub}, nil
+
+	case "reviews":
+		return parseAdminReviewsCmd(args[1:])
+
+	default:
+		return "help", nil, fmt.Errorf("%w: admin %q", ErrUnknownCommand, sub)
+	}
+}
+
 var ErrUnknownDay = errors.New("unknown day abbreviation")
 
 // parseDay expands day name abbreviations into their canonical form.
@@ -114,3 +182,123 @@ func parseDay(word string) (string, error) {
 		return "", fmt.Errorf("%w: %q", ErrUnknownDay, word)
 	}
 }
+
+// parseAdminReviewsCmd parses "admin reviews pending", "admin reviews approve
+// <id>", and "admin reviews reject <id>".
+func parseAdminReviewsCmd(args []string) (string, []string, error) {
+	if len(args) == 0 {
+		return "help", nil, fmt.Errorf("%w: wanted pending, approve, or reject", ErrInvalidArguments)
+	}
+
+	action := strings.ToLower(args[0])
+	switch action {
+	case "pending":
+		if len(args) > 1 {
+			return "help", nil, fmt.Errorf("%w: wanted no arguments", ErrInvalidArguments)
+		}
+		return "admin", []string{"reviews", action}, nil
+
+	case "approve", "reject":
+		if len(args) != 2 {
+			return "help", nil, fmt.Errorf("%w: wanted one review ID", ErrInvalidArguments)
+		}
+		// Review IDs are case-sensitive, so leave this one alone.
+		return "admin", []string{"reviews", action, args[1]}, nil
+
+	default:
+		return "help", nil, fmt.Errorf("%w: admin reviews %q", ErrUnknownCommand, action)
+	}
+}
+
+const (
+	defaultReviewsPerPage = 5
+	maxReviewsPerPage     = 20
+)
+
+// reviewsArgs are the options for get-reviews.
+type reviewsArgs struct {
+	Count int
+	After store.ReviewCursor
+	Since time.Time
+	Until time.Time
+}
+
+// String formats the options as a get-reviews command.
+func (a reviewsArgs) String() string {
+	cmd := "get-reviews"
+	if a.Count != defaultReviewsPerPage {
+		cmd += fmt.Sprintf(" %d", a.Count)
+	}
+	if a.After != "" {
+		cmd += " after " + string(a.After)
+	}
+	if !a.Since.IsZero() {
+		cmd += " since " + a.Since.Format(time.DateOnly)
+	}
+	if !a.Until.IsZero() {
+		cmd += " until " + a.Until.AddDate(0, 0, -1).Format(time.DateOnly)
+	}
+	return cmd
+}
+
+// parseReviewsArgs parses the arguments to get-reviews. They can come in any
+// order:
+//
+//   - a number of reviews to show, from 1 up to maxReviewsPerPage
+//   - "after CURSOR" to continue from where the previous page ended
+//   - "since YYYY-MM-DD" and "until YYYY-MM-DD" to limit the reviews to those
+//     submitted within those dates, inclusive
+func parseReviewsArgs(args []string) (reviewsArgs, error) {
+	parsed := reviewsArgs{Count: defaultReviewsPerPage}
+	seen := make(map[string]bool)
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+
+		if n, err := strconv.Atoi(arg); err == nil {
+			if seen["count"] || n < 1 {
+				return parsed, errors.New("wanted a positive integer")
+			}
+			seen["count"] = true
+			parsed.Count = min(n, maxReviewsPerPage)
+			continue
+		}
+
+		if seen[arg] {
+			return parsed, fmt.Errorf("%q given twice", arg)
+		}
+		seen[arg] = true
+
+		if i+1 >= len(args) {
+			return parsed, fmt.Errorf("wanted a value after %q", arg)
+		}
+		i++
+		value := args[i]
+
+		switch arg {
+		case "after":
+			cursor, err := store.ParseReviewCursor(value)
+			if err != nil {
+				return parsed, fmt.Errorf("wanted the value I sent at the end of the last page after %q", arg)
+			}
+			parsed.After = cursor
+
+		case "since", "until":
+			date, err := time.ParseInLocation(time.DateOnly, value, time.UTC)
+			if err != nil {
+				return parsed, fmt.Errorf("wanted a date like 2024-05-20 after %q", arg)
+			}
+			if arg == "since" {
+				parsed.Since = date
+			} else {
+				// Include the whole day.
+				parsed.Until = date.AddDate(0, 0, 1)
+			}
+
+		default:
+			return parsed, fmt.Errorf("unknown option %q", arg)
+		}
+	}
+
+	return parsed, nil
+}
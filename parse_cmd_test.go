@@ -2,8 +2,10 @@ package main
 
 import (
 	"testing"
+	"time"
 
 	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/store"
 )
 
 type parseResult struct {
@@ -14,12 +16,28 @@ type parseResult struct {
 var acceptedCommands = map[string]parseResult{
 	"subscribe":   {"subscribe", nil},
 	"unsubscribe": {"unsubscribe", nil},
+	"forget-me":   {"forget-me", nil},
+	"my-data":     {"my-data", nil},
+	"stay":        {"stay", nil},
 	"help":        {"help", nil},
 	"status":      {"status", nil},
 	"get-reviews": {"get-reviews", nil},
 	"cookie":      {"cookie", nil},
 	"version":     {"version", nil},
 
+	// Review IDs keep their case, and so does review content.
+	"my-reviews":                        {"my-reviews", nil},
+	"edit-review AbC123 Now It's Fixed": {"edit-review", []string{"AbC123", "Now It's Fixed"}},
+	"delete-review AbC123":              {"delete-review", []string{"AbC123"}},
+
+	// Deleting everything needs confirmation.
+	"forget-me confirm": {"forget-me", []string{"confirm"}},
+	// So does unsubscribing, but its preferences are kept.
+	"unsubscribe confirm": {"unsubscribe", []string{"confirm"}},
+
+	// Without any days, the user is asked which ones they'd like.
+	"schedule": {"schedule", nil},
+
 	// This command ignores its arguments.
 	"version info": {"version", nil},
 
@@ -27,6 +45,14 @@ var acceptedCommands = map[string]parseResult{
 	"skip tomorrow":   {"skip", []string{"tomorrow"}},
 	"unskip tomorrow": {"unskip", []string{"tomorrow"}},
 
+	"partners anyone":       {"partners", []string{"anyone"}},
+	"partners Alumni-Only":  {"partners", []string{"alumni-only"}},
+	"partners current-only": {"partners", []string{"current-only"}},
+
+	// Interests keep the user's own wording.
+	"interests":                          {"interests", nil},
+	"INTERESTS  Rust, Go, and Compilers": {"interests", []string{"Rust, Go, and Compilers"}},
+
 	// Schedules!
 	"schedule monday":         {"schedule", []string{"monday"}},
 	"schedule sunday":         {"schedule", []string{"sunday"}},
@@ -39,11 +65,14 @@ var acceptedCommands = map[string]parseResult{
 	// Don't squash spaces *inside* the review.
 	"add-review  :pear: ing    :robot:": {"add-review", []string{":pear: ing    :robot:"}},
 
-	"get-reviews 0":  {"get-reviews", []string{"0"}},
 	"get-reviews 1":  {"get-reviews", []string{"1"}},
 	"get-reviews 5":  {"get-reviews", []string{"5"}},
 	"get-reviews 10": {"get-reviews", []string{"10"}},
 
+	"get-reviews after sfvz2o-616263":                    {"get-reviews", []string{"after", "sfvz2o-616263"}},
+	"get-reviews 3 After SFVZ2O-616263 since 2024-01-01": {"get-reviews", []string{"3", "after", "sfvz2o-616263", "since", "2024-01-01"}},
+	"get-reviews until 2024-12-31 since 2024-01-01":      {"get-reviews", []string{"until", "2024-12-31", "since", "2024-01-01"}},
+
 	// Commands are case-insensitive.
 	"Help":      {"help", nil},
 	"hElP":      {"help", nil},
@@ -55,6 +84,16 @@ var acceptedCommands = map[string]parseResult{
 	// Review content *is* case-sensitive.
 	"add-review   I :heart: Pairing Bot!\n": {"add-review", []string{"I :heart: Pairing Bot!"}},
 
+	// Maintainer commands
+	"admin jobs":    {"admin", []string{"jobs"}},
+	"ADMIN Jobs":    {"admin", []string{"jobs"}},
+	"admin schema":  {"admin", []string{"schema"}},
+	"admin refresh": {"admin", []string{"refresh"}},
+
+	"admin reviews pending":        {"admin", []string{"reviews", "pending"}},
+	"admin reviews approve AbC123": {"admin", []string{"reviews", "approve", "AbC123"}},
+	"admin Reviews REJECT AbC123":  {"admin", []string{"reviews", "reject", "AbC123"}},
+
 	// We appreciate being appreciated
 	"thanks":    {"thanks", nil},
 	"thank you": {"thanks", nil},
@@ -80,7 +119,6 @@ var rejectedCommands = map[string]error{
 	// Funnily enough: nil, these *do* give you what you want!
 	"help me":       ErrInvalidArguments,
 	"halp":          ErrUnknownCommand,
-	"schedule":      ErrInvalidArguments,
 	"schedule help": ErrUnknownDay,
 
 	// Unexpected arguments
@@ -90,6 +128,19 @@ var rejectedCommands = map[string]error{
 	// Did they really want `schedule`?
 	"subscribe tue":   ErrInvalidArguments,
 	"unsubscribe thu": ErrInvalidArguments,
+	"forget-me now":   ErrInvalidArguments,
+	"my-data please":  ErrInvalidArguments,
+	"stay forever":    ErrInvalidArguments,
+
+	"my-reviews all":        ErrInvalidArguments,
+	"edit-review":           ErrInvalidArguments,
+	"edit-review AbC123":    ErrInvalidArguments,
+	"delete-review":         ErrInvalidArguments,
+	"delete-review AbC 123": ErrInvalidArguments,
+
+	"partners":               ErrInvalidArguments,
+	"partners alumni":        ErrInvalidArguments,
+	"partners anyone please": ErrInvalidArguments,
 
 	// (Un)skipping requires an argument.
 	"skip":   ErrInvalidArguments,
@@ -100,13 +151,32 @@ var rejectedCommands = map[string]error{
 	"unskip next": ErrInvalidArguments,
 
 	// This is not the way to delete reviews you don't like 😛
-	"get-reviews -1":  ErrInvalidArguments,
-	"get-reviews -10": ErrInvalidArguments,
+	"get-reviews 0":      ErrInvalidArguments,
+	"get-reviews -1":     ErrInvalidArguments,
+	"get-reviews page 2": ErrInvalidArguments,
+	"get-reviews -10":    ErrInvalidArguments,
 
 	"get-reviews 1 2": ErrInvalidArguments,
 
+	"get-reviews page":              ErrInvalidArguments,
+	"get-reviews page 0":            ErrInvalidArguments,
+	"get-reviews page 2 page 3":     ErrInvalidArguments,
+	"get-reviews since yesterday":   ErrInvalidArguments,
+	"get-reviews before 2024-01-01": ErrInvalidArguments,
+
 	"add-review": ErrInvalidArguments,
 
+	// Admin commands need a known subcommand.
+	"admin":          ErrInvalidArguments,
+	"admin jobs now": ErrInvalidArguments,
+	"admin takeover": ErrUnknownCommand,
+
+	"admin reviews":               ErrInvalidArguments,
+	"admin reviews pending 2":     ErrInvalidArguments,
+	"admin reviews approve":       ErrInvalidArguments,
+	"admin reviews reject a b":    ErrInvalidArguments,
+	"admin reviews publish AbC12": ErrUnknownCommand,
+
 	// Unknown commands
 	"scheduleing monday": ErrUnknownCommand,
 	"schedul monday":     ErrUnknownCommand,
@@ -125,3 +195,47 @@ func TestParseCmdReject(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseReviewsArgs(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		got, err := parseReviewsArgs(nil)
+		assert.NoError(t, err)
+		assert.Equal(t, got, reviewsArgs{Count: defaultReviewsPerPage})
+		assert.Equal(t, got.String(), "get-reviews")
+	})
+
+	t.Run("count is capped", func(t *testing.T) {
+		got, err := parseReviewsArgs([]string{"1000"})
+		assert.NoError(t, err)
+		assert.Equal(t, got.Count, maxReviewsPerPage)
+	})
+
+	t.Run("dates are inclusive", func(t *testing.T) {
+		got, err := parseReviewsArgs([]string{"since", "2024-01-01", "until", "2024-01-31", "after", "sfvz2o-616263"})
+		assert.NoError(t, err)
+		assert.Equal(t, got, reviewsArgs{
+			Count: defaultReviewsPerPage,
+			After: must(store.ParseReviewCursor("sfvz2o-616263")),
+			Since: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			Until: time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC),
+		})
+
+		// Formatting them gives back an equivalent command.
+		assert.Equal(t, got.String(), "get-reviews after sfvz2o-616263 since 2024-01-01 until 2024-01-31")
+	})
+
+	for name, args := range map[string][]string{
+		"zero reviews":   {"0"},
+		"negative":       {"-3"},
+		"two counts":     {"3", "4"},
+		"bad cursor":     {"after", "page-2"},
+		"missing cursor": {"after"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseReviewsArgs(args)
+			if err == nil {
+				t.Errorf("expected an error for %q", args)
+			}
+		})
+	}
+}
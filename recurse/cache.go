@@ -0,0 +1,205 @@
+package recurse
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// A Cache remembers the results of the Recurse API calls that the bot makes
+// over and over, so that each one doesn't page through the whole directory.
+//
+// Results are fresh for the TTL. After that, they're still served for up to
+// the stale period while a fresh copy is fetched in the background. Results
+// older than that are fetched again before returning.
+//
+// Methods that aren't cached are passed through to the Client.
+type Cache struct {
+	*Client
+
+	active  cacheEntry[activeProfiles]
+	batches cacheEntry[[]Batch]
+}
+
+// activeProfiles are the profiles of the recursers currently at RC, indexed
+// by Zulip ID.
+type activeProfiles struct {
+	list      []Profile
+	byZulipID map[int64]Profile
+}
+
+// NewCache wraps the client with a cache. Results are fresh for ttl and can be
+// served stale for staleFor after that.
+func NewCache(client *Client, ttl, staleFor time.Duration) *Cache {
+	c := &Cache{Client: client}
+
+	c.active = cacheEntry[activeProfiles]{
+		name:     "active recursers",
+		ttl:      ttl,
+		staleFor: staleFor,
+		load: func(ctx context.Context) (activeProfiles, error) {
+			list, err := client.ActiveRecursers(ctx)
+			if err != nil {
+				return activeProfiles{}, err
+			}
+
+			byZulipID := make(map[int64]Profile, len(list))
+			for _, p := range list {
+				byZulipID[p.ZulipID] = p
+			}
+			return activeProfiles{list, byZulipID}, nil
+		},
+	}
+
+	c.batches = cacheEntry[[]Batch]{
+		name:     "batches",
+		ttl:      ttl,
+		staleFor: staleFor,
+		load:     client.AllBatches,
+	}
+
+	return c
+}
+
+// ActiveRecursers returns the profiles for all recursers currently at RC.
+func (c *Cache) ActiveRecursers(ctx context.Context) ([]Profile, error) {
+	active, err := c.active.get(ctx)
+	return active.list, err
+}
+
+// ActiveProfile returns the profile of the recurser with the Zulip ID, if
+// they're currently at RC.
+func (c *Cache) ActiveProfile(ctx context.Context, zulipID int64) (Profile, bool, error) {
+	active, err := c.active.get(ctx)
+	if err != nil {
+		return Profile{}, false, err
+	}
+
+	p, ok := active.byZulipID[zulipID]
+	return p, ok, nil
+}
+
+// IsCurrentlyAtRC returns whether the user's Zulip ID belongs to a recurser
+// currently at RC.
+func (c *Cache) IsCurrentlyAtRC(ctx context.Context, zulipID int64) (bool, error) {
+	_, ok, err := c.ActiveProfile(ctx, zulipID)
+	return ok, err
+}
+
+// AllBatches returns all RC batches up to the current batch with the most
+// recent batch first.
+func (c *Cache) AllBatches(ctx context.Context) ([]Batch, error) {
+	return c.batches.get(ctx)
+}
+
+// Invalidate forgets every cached result, so the next call of each method
+// fetches fresh data.
+func (c *Cache) Invalidate() {
+	c.active.invalidate()
+	c.batches.invalidate()
+}
+
+// cacheEntry holds one cached result and keeps it up to date.
+type cacheEntry[T any] struct {
+	name          string
+	ttl, staleFor time.Duration
+	load          func(context.Context) (T, error)
+
+	// fetch is held while fetching synchronously, so that concurrent callers
+	// with nothing usable wait for one fetch instead of each making their own.
+	fetch sync.Mutex
+
+	mu         sync.Mutex
+	value      T
+	fetchedAt  time.Time
+	refreshing bool
+	// generation changes on invalidation, so that fetches that started
+	// earlier don't store their (possibly outdated) results.
+	generation int
+}
+
+func (e *cacheEntry[T]) get(ctx context.Context) (T, error) {
+	if value, ok := e.cached(ctx); ok {
+		return value, nil
+	}
+
+	e.fetch.Lock()
+	defer e.fetch.Unlock()
+
+	// Someone else may have fetched it while we were waiting.
+	if value, ok := e.cached(ctx); ok {
+		return value, nil
+	}
+
+	return e.refresh(ctx)
+}
+
+// cached returns the current value if it's usable. If it's stale, this also
+// starts refreshing it in the background.
+func (e *cacheEntry[T]) cached(ctx context.Context) (T, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var zero T
+	if e.fetchedAt.IsZero() {
+		return zero, false
+	}
+
+	age := time.Since(e.fetchedAt)
+	if age < e.ttl {
+		return e.value, true
+	}
+	if age >= e.ttl+e.staleFor {
+		return zero, false
+	}
+
+	if !e.refreshing {
+		e.refreshing = true
+
+		// The refresh outlives the request that noticed the value was stale.
+		ctx := context.WithoutCancel(ctx)
+		go func() {
+			if _, err := e.refresh(ctx); err != nil {
+				log.Printf("Could not refresh cached %s: %s", e.name, err)
+			}
+
+			e.mu.Lock()
+			e.refreshing = false
+			e.mu.Unlock()
+		}()
+	}
+	return e.value, true
+}
+
+// refresh loads the value and caches it.
+func (e *cacheEntry[T]) refresh(ctx context.Context) (T, error) {
+	e.mu.Lock()
+	generation := e.generation
+	e.mu.Unlock()
+
+	value, err := e.load(ctx)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err != nil {
+		return value, err
+	}
+
+	if e.generation == generation {
+		e.value = value
+		e.fetchedAt = time.Now()
+	}
+	return value, nil
+}
+
+func (e *cacheEntry[T]) invalidate() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var zero T
+	e.value = zero
+	e.fetchedAt = time.Time{}
+	e.generation++
+}
@@ -0,0 +1,134 @@
+package recurse_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/recurse"
+)
+
+func cachedClient(t *testing.T, ttl, staleFor time.Duration) (*recurse.Cache, *MockServer) {
+	srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		var err error
+		switch r.URL.Path {
+		case "/profiles":
+			err = json.NewEncoder(w).Encode(fakeProfiles(t, 3))
+		case "/batches":
+			err = json.NewEncoder(w).Encode(loadJSON[[]recurse.Batch](t, "testdata/batches.json"))
+		default:
+			t.Errorf("unexpected request for %s", r.URL.Path)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := recurse.NewClient(
+		recurse.StaticAccessToken("fake-access-token"),
+		recurse.WithHTTP(srv.Client()),
+		recurse.WithBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return recurse.NewCache(client, ttl, staleFor), srv
+}
+
+// waitForRequests waits for background refreshes to reach the server.
+func (m *MockServer) waitForRequests(n int64) {
+	m.t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for m.requestCount.Load() < n {
+		if time.Now().After(deadline) {
+			m.t.Fatalf("timed out waiting for %d requests, got %d", n, m.requestCount.Load())
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("warm cache doesn't fetch", func(t *testing.T) {
+		cache, srv := cachedClient(t, time.Hour, time.Hour)
+		defer srv.AssertRequestCount(2)
+
+		for range 3 {
+			profiles, err := cache.ActiveRecursers(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, profiles, fakeProfiles(t, 3))
+		}
+
+		profile, ok, err := cache.ActiveProfile(ctx, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, ok, true)
+		assert.Equal(t, profile.Name, "Name 2")
+
+		atRC, err := cache.IsCurrentlyAtRC(ctx, 1234)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, atRC, false)
+
+		for range 2 {
+			batches, err := cache.AllBatches(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, batches[0].Name, "Fall 2, 2023")
+		}
+	})
+
+	t.Run("stale while revalidating", func(t *testing.T) {
+		cache, srv := cachedClient(t, 10*time.Millisecond, time.Hour)
+		defer srv.AssertRequestCount(2)
+
+		if _, err := cache.AllBatches(ctx); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+
+		// This is served from the cache, and refreshes it in the background.
+		batches, err := cache.AllBatches(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(batches) > 0, true)
+
+		srv.waitForRequests(2)
+	})
+
+	t.Run("too stale to serve", func(t *testing.T) {
+		cache, srv := cachedClient(t, 10*time.Millisecond, 0)
+		defer srv.AssertRequestCount(2)
+
+		for range 2 {
+			if _, err := cache.ActiveRecursers(ctx); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+	})
+
+	t.Run("invalidate", func(t *testing.T) {
+		cache, srv := cachedClient(t, time.Hour, time.Hour)
+		defer srv.AssertRequestCount(2)
+
+		for range 2 {
+			if _, err := cache.ActiveRecursers(ctx); err != nil {
+				t.Fatal(err)
+			}
+			cache.Invalidate()
+		}
+	})
+}
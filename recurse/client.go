@@ -51,23 +51,104 @@ func NewClient(accessToken AccessTokenFunc, opts ...ClientOpt) (*Client, error)
 //
 // https://github.com/recursecenter/wiki/wiki/Recurse-Center-API#Profiles
 type Profile struct {
-	Name    string `json:"name"`
-	ZulipID int64  `json:"zulip_id"`
+	ID       int64  `json:"id"`
+	Name     string `json:"name"`
+	ZulipID  int64  `json:"zulip_id"`
+	Pronouns string `json:"pronouns"`
+
+	// ImagePath is the URL of the Recurser's profile picture.
+	ImagePath string `json:"image_path"`
+
+	// Stints are the Recurser's times at RC, oldest first.
+	Stints []Stint `json:"stints"`
+}
+
+// StintOn returns the stint that includes the given day, if there is one.
+func (p Profile) StintOn(day time.Time) (Stint, bool) {
+	for _, s := range p.Stints {
+		if s.Includes(day) {
+			return s, true
+		}
+	}
+	return Stint{}, false
+}
+
+// StintIn returns the Recurser's stint in the batch, if there is one.
+func (p Profile) StintIn(batchID int64) (Stint, bool) {
+	for _, s := range p.Stints {
+		if s.Batch != nil && s.Batch.ID == batchID {
+			return s, true
+		}
+	}
+	return Stint{}, false
+}
+
+// A Stint is one continuous period someone spent at RC, like attending a batch
+// or working as faculty.
+//
+// https://github.com/recursecenter/wiki/wiki/Recurse-Center-API#Profiles
+type Stint struct {
+	ID int64 `json:"id"`
+	// Type is "retreat" for Recursers attending a batch. Other values, like
+	// "residency" and "employment", are for other roles at RC.
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	ForHalfBatch bool   `json:"for_half_batch"`
+	InProgress   bool   `json:"in_progress"`
+	// InPerson is false for remote stints.
+	InPerson bool `json:"in_person"`
+
+	StartDate Datestamp `json:"start_date"`
+	// EndDate is the zero time for stints with no planned end, like employment.
+	EndDate Datestamp `json:"end_date"`
+
+	// Batch is the batch that the stint was part of, or nil for stints that
+	// weren't part of one. Only its ID and Name are set.
+	Batch *Batch `json:"batch"`
+}
+
+// Includes returns whether the stint covers the given day, including its first
+// and last days.
+func (s Stint) Includes(day time.Time) bool {
+	date := day.UTC().Truncate(24 * time.Hour)
+	start, end := time.Time(s.StartDate), time.Time(s.EndDate)
+	return !date.Before(start) && (end.IsZero() || !date.After(end))
 }
 
 // ActiveRecursers fetches the profiles for all recursers currently at RC.
 //
 // https://github.com/recursecenter/wiki/wiki/Recurse-Center-API#search
 func (c *Client) ActiveRecursers(ctx context.Context) ([]Profile, error) {
+	params := make(url.Values)
+	params.Set("scope", "current")
+	params.Set("role", "recurser")
+
+	return c.searchProfiles(ctx, "active recursers", params)
+}
+
+// BatchRecursers fetches the profiles for all recursers who attended the
+// batch, including anyone who is still there.
+//
+// https://github.com/recursecenter/wiki/wiki/Recurse-Center-API#search
+func (c *Client) BatchRecursers(ctx context.Context, batchID int64) ([]Profile, error) {
+	params := make(url.Values)
+	params.Set("batch_id", strconv.FormatInt(batchID, 10))
+	params.Set("role", "recurser")
+
+	return c.searchProfiles(ctx, fmt.Sprintf("recursers in batch %d", batchID), params)
+}
+
+// searchProfiles loads every page of profiles matching the search params.
+func (c *Client) searchProfiles(ctx context.Context, what string, params url.Values) ([]Profile, error) {
 	var profiles []Profile
 	offset := 0
 	limit := 50
 	hasMore := true
 
 	for hasMore {
-		next, err := c.activeRecursers(ctx, offset, limit)
+		next, err := c.searchProfilesPage(ctx, params, offset, limit)
 		if err != nil {
-			return nil, fmt.Errorf("get active recursers (offset=%d): %w", offset, err)
+			return nil, fmt.Errorf("get %s (offset=%d): %w", what, offset, err)
 		}
 
 		// Move the offset cursor up by the number of profiles we got.
@@ -82,15 +163,13 @@ func (c *Client) ActiveRecursers(ctx context.Context) ([]Profile, error) {
 	return profiles, nil
 }
 
-// activeRecursers loads one page of profiles for recursers currently at RC.
+// searchProfilesPage loads one page of profiles matching the search params.
 //
 // https://github.com/recursecenter/wiki/wiki/Recurse-Center-API#search
-func (c *Client) activeRecursers(ctx context.Context, offset int, limit int) ([]Profile, error) {
-	params := make(url.Values)
-	params.Set("scope", "current")
+func (c *Client) searchProfilesPage(ctx context.Context, search url.Values, offset int, limit int) ([]Profile, error) {
+	params := maps.Clone(search)
 	params.Set("offset", strconv.Itoa(offset))
 	params.Set("limit", strconv.Itoa(limit))
-	params.Set("role", "recurser")
 
 	resp, err := c.get(ctx, "profiles", params)
 	if err != nil {
@@ -144,13 +223,28 @@ func (d Datestamp) Equal(other Datestamp) bool {
 //
 // https://github.com/recursecenter/wiki/wiki/Recurse-Center-API#Batches
 type Batch struct {
+	ID        int64     `json:"id"`
 	Name      string    `json:"name"`
 	StartDate Datestamp `json:"start_date"`
+	EndDate   Datestamp `json:"end_date"`
 }
 
-// IsMini returns whether the batch was a mini batch.
+// IsMini returns whether the batch was a mini batch. Mini batches last one
+// week, and other batches that short (like some summer specials) count too.
 func (b Batch) IsMini() bool {
-	return strings.HasPrefix(b.Name, "Mini")
+	if strings.HasPrefix(b.Name, "Mini") {
+		return true
+	}
+
+	start, end := time.Time(b.StartDate), time.Time(b.EndDate)
+	return !end.IsZero() && end.Sub(start) < 7*24*time.Hour
+}
+
+// IsFirstWeek returns whether the time is within the batch's first week.
+func (b Batch) IsFirstWeek(now time.Time) bool {
+	activeTime := now.Sub(time.Time(b.StartDate))
+	week := 7 * 24 * time.Hour
+	return 0 <= activeTime && activeTime < 1*week
 }
 
 // IsSecondWeek returns whether the time is within the batch's second week.
@@ -160,6 +254,14 @@ func (b Batch) IsSecondWeek(now time.Time) bool {
 	return 1*week < activeTime && activeTime < 2*week
 }
 
+// EndsWithin returns whether the batch's last day is between now and d from
+// now. A batch ending today counts, since its last day isn't over yet.
+func (b Batch) EndsWithin(now time.Time, d time.Duration) bool {
+	lastDay := time.Time(b.EndDate)
+	today := now.UTC().Truncate(24 * time.Hour)
+	return !lastDay.Before(today) && lastDay.Before(now.Add(d))
+}
+
 // AllBatches returns all RC batches up to the current batch with the most
 // recent batch first.
 //
@@ -180,9 +282,8 @@ func (c *Client) AllBatches(ctx context.Context) ([]Batch, error) {
 func (c *Client) IsCurrentlyAtRC(ctx context.Context, zulipID int64) (bool, error) {
 	// In practice, there aren't more than a few pages of Recursers currently
 	// at RC. To save us from another pagination cursor, we can load everyone
-	// at once and then scan for the Zulip ID.
-	//
-	// TODO(@jdkaplan): Query by RC ID instead to avoid iterating at all
+	// at once and then scan for the Zulip ID. Use a Cache to avoid loading
+	// everyone for each lookup.
 	active, err := c.ActiveRecursers(ctx)
 	if err != nil {
 		return false, err
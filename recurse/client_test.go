@@ -315,6 +315,18 @@ func TestBatch_IsMini(t *testing.T) {
 		batch := batches[0]
 		assert.Equal(t, batch.IsMini(), false)
 	})
+
+	t.Run("short special batch", func(t *testing.T) {
+		batch := mustJSON[recurse.Batch](t, `
+		  {
+		    "id": 170,
+		    "name": "Summer Special, 2024",
+		    "start_date": "2024-07-08",
+		    "end_date": "2024-07-12"
+		  }
+		`)
+		assert.Equal(t, batch.IsMini(), true)
+	})
 }
 
 func mustJSON[T any](t *testing.T, data string) T {
@@ -351,4 +363,108 @@ func TestBatch_IsSecondWeek(t *testing.T) {
 	assert.Equal(t, batch.IsSecondWeek(week1cron), false)
 	assert.Equal(t, batch.IsSecondWeek(week2cron), true)
 	assert.Equal(t, batch.IsSecondWeek(week3cron), false)
+
+	assert.Equal(t, batch.IsFirstWeek(week1cron), true)
+	assert.Equal(t, batch.IsFirstWeek(week2cron), false)
+	assert.Equal(t, batch.IsFirstWeek(week1cron.AddDate(0, 0, -7)), false)
+}
+
+func TestClient_BatchRecursers(t *testing.T) {
+	srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodGet)
+		assert.Equal(t, r.URL.Path, "/profiles")
+
+		params := url.Values{
+			"access_token": []string{"fake-access-token"},
+			"batch_id":     []string{"166"},
+			"role":         []string{"recurser"},
+			"offset":       []string{"0"},
+			"limit":        []string{"50"},
+		}
+		assert.Equal(t, r.URL.Query(), params)
+
+		err := json.NewEncoder(w).Encode(fakeProfiles(t, 3))
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	defer srv.AssertRequestCount(1)
+
+	client, err := recurse.NewClient(
+		recurse.StaticAccessToken("fake-access-token"),
+		recurse.WithHTTP(srv.Client()),
+		recurse.WithBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profiles, err := client.BatchRecursers(context.Background(), 166)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, profiles, fakeProfiles(t, 3))
+}
+
+func TestBatch_EndsWithin(t *testing.T) {
+	batch := mustJSON[recurse.Batch](t, `
+	  {
+	    "id": 166,
+	    "name": "Summer 1, 2024",
+	    "start_date": "2024-05-20",
+	    "end_date": "2024-08-09"
+	  }
+	`)
+
+	week := 7 * 24 * time.Hour
+
+	for now, expected := range map[string]bool{
+		"2024-07-29T15:00:00Z": false, // More than a week before
+		"2024-08-05T15:00:00Z": true,  // The Monday before
+		"2024-08-09T15:00:00Z": true,  // The last day itself
+		"2024-08-10T15:00:00Z": false, // The day after
+	} {
+		t.Run(now, func(t *testing.T) {
+			assert.Equal(t, batch.EndsWithin(must(time.Parse(time.RFC3339, now)), week), expected)
+		})
+	}
+}
+
+func TestProfile_Stints(t *testing.T) {
+	profiles := loadJSON[[]recurse.Profile](t, "testdata/profiles.json")
+	profile := profiles[0]
+
+	assert.Equal(t, profile.ID, int64(5678))
+	assert.Equal(t, profile.Pronouns, "she/her")
+	assert.Equal(t, profile.ImagePath, "https://assets.recurse.example.net/profile-images/5678.jpg")
+	assert.Equal(t, len(profile.Stints), 3)
+
+	remote := profile.Stints[0]
+	assert.Equal(t, remote.InPerson, false)
+	assert.Equal(t, remote.Batch.Name, "Summer 1, 2023")
+	assert.Equal(t, time.Time(remote.EndDate), time.Date(2023, time.August, 4, 0, 0, 0, 0, time.UTC))
+
+	t.Run("stint in batch", func(t *testing.T) {
+		stint, ok := profile.StintIn(154)
+		assert.Equal(t, ok, true)
+		assert.Equal(t, stint.ForHalfBatch, true)
+
+		_, ok = profile.StintIn(153)
+		assert.Equal(t, ok, false)
+	})
+
+	for day, expected := range map[string]int64{
+		"2023-05-15": 1001, // First day
+		"2023-08-04": 1001, // Last day
+		"2023-09-01": 0,    // Between batches
+		"2023-10-27": 1002,
+		"2024-06-01": 1003, // Employment has no end date
+	} {
+		t.Run("stint on "+day, func(t *testing.T) {
+			stint, ok := profile.StintOn(must(time.Parse(time.DateOnly, day)).Add(15 * time.Hour))
+			assert.Equal(t, ok, expected != 0)
+			assert.Equal(t, stint.ID, expected)
+		})
+	}
 }
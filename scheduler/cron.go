@@ -0,0 +1,197 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// A Schedule is a parsed cron expression in the standard five-field format:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Each field can be "*", a number, a range ("1-5"), a list ("mon,wed,fri"),
+// or any of those with a step ("*/15", "0-30/10"). Months and weekdays may be
+// given by their three-letter English names.
+//
+// As in Vixie cron, if both day-of-month and day-of-week are restricted (not
+// "*"), a day matches if it matches either field.
+type Schedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// domStar and dowStar record whether the day fields were unrestricted,
+	// which changes how they combine.
+	domStar, dowStar bool
+}
+
+type field struct {
+	min, max int
+	names    map[string]int
+}
+
+var (
+	minuteField = field{min: 0, max: 59}
+	hourField   = field{min: 0, max: 23}
+	domField    = field{min: 1, max: 31}
+	monthField  = field{min: 1, max: 12, names: map[string]int{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Both 0 and 7 mean Sunday.
+	dowField = field{min: 0, max: 7, names: map[string]int{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Parse parses a five-field cron expression.
+func Parse(expr string) (Schedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("%w: %q: wanted 5 fields, got %d", ErrInvalidExpression, expr, len(fields))
+	}
+
+	var s Schedule
+	var err error
+
+	if s.minute, err = minuteField.parse(fields[0]); err != nil {
+		return Schedule{}, fmt.Errorf("%w: %q: minute: %w", ErrInvalidExpression, expr, err)
+	}
+	if s.hour, err = hourField.parse(fields[1]); err != nil {
+		return Schedule{}, fmt.Errorf("%w: %q: hour: %w", ErrInvalidExpression, expr, err)
+	}
+	if s.dom, err = domField.parse(fields[2]); err != nil {
+		return Schedule{}, fmt.Errorf("%w: %q: day of month: %w", ErrInvalidExpression, expr, err)
+	}
+	if s.month, err = monthField.parse(fields[3]); err != nil {
+		return Schedule{}, fmt.Errorf("%w: %q: month: %w", ErrInvalidExpression, expr, err)
+	}
+	if s.dow, err = dowField.parse(fields[4]); err != nil {
+		return Schedule{}, fmt.Errorf("%w: %q: day of week: %w", ErrInvalidExpression, expr, err)
+	}
+
+	// Fold Sunday-as-7 into Sunday-as-0 so that time.Weekday lines up.
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1 << 0
+	}
+
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	return s, nil
+}
+
+// parse converts one field of an expression into a bit set of allowed values.
+func (f field) parse(s string) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(s, ",") {
+		rng, stepStr, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepStr)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepStr)
+			}
+		}
+
+		var lo, hi int
+		switch {
+		case rng == "*":
+			lo, hi = f.min, f.max
+		case strings.Contains(rng, "-"):
+			loStr, hiStr, _ := strings.Cut(rng, "-")
+			var err error
+			if lo, err = f.value(loStr); err != nil {
+				return 0, err
+			}
+			if hi, err = f.value(hiStr); err != nil {
+				return 0, err
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			var err error
+			if lo, err = f.value(rng); err != nil {
+				return 0, err
+			}
+			// "5/10" means "every 10, starting at 5".
+			hi = lo
+			if hasStep {
+				hi = f.max
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// value parses a single number or name within the field's bounds.
+func (f field) value(s string) (int, error) {
+	if v, ok := f.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// Next returns the earliest time strictly after t (to the minute) that
+// matches the schedule, in t's location. It returns the zero time if there is
+// no such time within the next five years (e.g., "0 0 30 feb *").
+func (s Schedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	// Start at the beginning of the next minute.
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches checks both day fields with cron's either-or rule.
+func (s Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+
+	if s.domStar || s.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+func has(bits uint64, v int) bool {
+	return bits&(1<<v) != 0
+}
@@ -0,0 +1,103 @@
+package scheduler_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/scheduler"
+)
+
+func must[T any](v T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return v
+}
+
+func TestSchedule_Next(t *testing.T) {
+	// Tuesday, May 21, 2024
+	start := must(time.Parse(time.RFC3339, "2024-05-21T14:07:30Z"))
+
+	for expr, expected := range map[string]string{
+		"* * * * *":    "2024-05-21T14:08:00Z",
+		"*/15 * * * *": "2024-05-21T14:15:00Z",
+		"0 4 * * *":    "2024-05-22T04:00:00Z",
+		"0 14 * * *":   "2024-05-22T14:00:00Z",
+		"30 14 * * *":  "2024-05-21T14:30:00Z",
+
+		// Weekdays, by number and name (matching cron.yaml)
+		"0 16 * * sat": "2024-05-25T16:00:00Z",
+		"0 18 * * TUE": "2024-05-21T18:00:00Z",
+		"0 18 * * 4":   "2024-05-23T18:00:00Z",
+		"0 0 * * 7":    "2024-05-26T00:00:00Z",
+		"0 9 * * 1-5":  "2024-05-22T09:00:00Z",
+
+		// Lists, ranges, and steps
+		"0,45 14 * * *":   "2024-05-21T14:45:00Z",
+		"10-20/5 * * * *": "2024-05-21T14:10:00Z",
+		"5/20 * * * *":    "2024-05-21T14:25:00Z",
+
+		// Months and days of the month
+		"0 0 1 * *":    "2024-06-01T00:00:00Z",
+		"0 0 1 jan *":  "2025-01-01T00:00:00Z",
+		"0 0 29 feb *": "2028-02-29T00:00:00Z",
+		"0 0 31 * *":   "2024-05-31T00:00:00Z",
+
+		// Either day field may match when both are restricted.
+		"0 0 1 * mon":  "2024-05-27T00:00:00Z",
+		"0 0 22 * fri": "2024-05-22T00:00:00Z",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			schedule, err := scheduler.Parse(expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			actual := schedule.Next(start)
+			assert.Equal(t, actual.Format(time.RFC3339), expected)
+		})
+	}
+}
+
+func TestSchedule_Next_location(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	schedule := must(scheduler.Parse("0 0 * * *"))
+
+	// Midnight in New York is 04:00 UTC during daylight saving time.
+	start := must(time.Parse(time.RFC3339, "2024-05-21T14:00:00Z")).In(ny)
+	actual := schedule.Next(start)
+
+	assert.Equal(t, actual.UTC().Format(time.RFC3339), "2024-05-22T04:00:00Z")
+}
+
+func TestSchedule_Next_never(t *testing.T) {
+	schedule := must(scheduler.Parse("0 0 30 feb *"))
+	assert.Equal(t, schedule.Next(time.Now()).IsZero(), true)
+}
+
+func TestParse_invalid(t *testing.T) {
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"* * * * someday",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"a-b * * * *",
+	} {
+		t.Run(expr, func(t *testing.T) {
+			_, err := scheduler.Parse(expr)
+			assert.ErrorIs(t, err, scheduler.ErrInvalidExpression)
+		})
+	}
+}
@@ -0,0 +1,195 @@
+// Package scheduler runs jobs on cron schedules within the Pairing Bot
+// process, as an alternative to an external scheduler like App Engine cron.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// A Job is a function to run on a schedule.
+type Job struct {
+	// Name identifies the job in logs.
+	Name string
+
+	// Schedule is a cron expression (see Parse).
+	Schedule string
+
+	// Location is the time zone for interpreting Schedule. The default is UTC.
+	Location *time.Location
+
+	// CatchUp is how late a run may start after its scheduled time. If the
+	// process wasn't running (or the previous run was still going) when the
+	// job came due, it runs as soon as possible if it's still within this
+	// window. Multiple missed runs are coalesced into one. Zero disables
+	// catching up: missed runs are skipped.
+	//
+	// The scheduler doesn't remember anything between processes, so a run
+	// that's still within the window when the process restarts looks missed.
+	// Set Finished to avoid running it again.
+	CatchUp time.Duration
+
+	// Finished reports whether the run scheduled for the given time has
+	// already finished, for example in an earlier process. Catch-up runs are
+	// skipped if it returns true. If it's nil or fails, the run goes ahead.
+	Finished func(ctx context.Context, scheduled time.Time) (bool, error)
+
+	// Run is the job itself.
+	Run func(context.Context) error
+}
+
+// job is a Job with its schedule parsed.
+type job struct {
+	Job
+	schedule Schedule
+}
+
+// A Scheduler runs a set of jobs on their schedules.
+type Scheduler struct {
+	jobs []job
+}
+
+// New validates the job definitions and creates a Scheduler for them.
+func New(jobs []Job) (*Scheduler, error) {
+	s := &Scheduler{}
+
+	for _, j := range jobs {
+		schedule, err := Parse(j.Schedule)
+		if err != nil {
+			return nil, fmt.Errorf("job %q: %w", j.Name, err)
+		}
+		if j.Location == nil {
+			j.Location = time.UTC
+		}
+		if j.Run == nil {
+			return nil, fmt.Errorf("job %q: missing Run function", j.Name)
+		}
+
+		s.jobs = append(s.jobs, job{Job: j, schedule: schedule})
+	}
+
+	return s, nil
+}
+
+// Run starts all of the jobs and blocks until the context is done. Each job
+// runs in its own goroutine, and a job never overlaps with itself.
+func (s *Scheduler) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, j := range s.jobs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j.loop(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// loop runs the job each time it comes due until the context is done.
+func (j job) loop(ctx context.Context) {
+	next := j.next(time.Now().Add(-j.CatchUp))
+
+	for {
+		if next.IsZero() {
+			slog.Error("Job will never run again", slog.String("job", j.Name), slog.String("schedule", j.Schedule))
+			return
+		}
+
+		slog.Info("Scheduled next job run", slog.String("job", j.Name), slog.Time("next", next))
+
+		if err := sleepUntil(ctx, next); err != nil {
+			return
+		}
+
+		start := time.Now()
+		late := start.Sub(next)
+		if late > time.Minute {
+			slog.Info("Catching up on missed job run", slog.String("job", j.Name), slog.Time("scheduled", next), slog.Duration("late", late))
+		}
+
+		if late > time.Minute && j.finished(ctx, next) {
+			slog.Info("Skipping missed job run that already finished", slog.String("job", j.Name), slog.Time("scheduled", next))
+		} else if err := j.Run(WithScheduledTime(ctx, next)); err != nil {
+			slog.Error("Job failed", slog.String("job", j.Name), slog.Any("error", err))
+		} else {
+			slog.Info("Job finished", slog.String("job", j.Name), slog.Duration("duration", time.Since(start)))
+		}
+
+		// Anything that came due while the job was running is only worth
+		// running if it's still within the catch-up window.
+		after := next
+		if cutoff := time.Now().Add(-j.CatchUp); cutoff.After(after) {
+			after = cutoff
+		}
+		next = j.next(after)
+	}
+}
+
+// finished returns whether the run scheduled for t is known to have finished
+// already.
+func (j job) finished(ctx context.Context, t time.Time) bool {
+	if j.Finished == nil {
+		return false
+	}
+
+	done, err := j.Finished(ctx, t)
+	if err != nil {
+		slog.Warn("Could not check whether job run already finished", slog.String("job", j.Name), slog.Time("scheduled", t), slog.Any("error", err))
+		return false
+	}
+	return done
+}
+
+// next returns the first scheduled time after t. If that time has already
+// passed, it skips ahead to the latest time that has passed, so that any
+// number of missed runs results in a single catch-up run.
+func (j job) next(t time.Time) time.Time {
+	now := time.Now()
+
+	next := j.schedule.Next(t.In(j.Location))
+	for !next.IsZero() && !next.After(now) {
+		later := j.schedule.Next(next)
+		if later.IsZero() || later.After(now) {
+			break
+		}
+		next = later
+	}
+	return next
+}
+
+// scheduledKey is the context key for the time a job run was scheduled for.
+type scheduledKey struct{}
+
+// WithScheduledTime records the time that a job run was scheduled for. This
+// can be well before the run actually starts, when it's catching up.
+func WithScheduledTime(ctx context.Context, t time.Time) context.Context {
+	return context.WithValue(ctx, scheduledKey{}, t)
+}
+
+// ScheduledTime returns the time that the job run was scheduled for, if it's
+// known.
+func ScheduledTime(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(scheduledKey{}).(time.Time)
+	return t, ok
+}
+
+// sleepUntil blocks until the time arrives or the context is done.
+func sleepUntil(ctx context.Context, t time.Time) error {
+	d := time.Until(t)
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
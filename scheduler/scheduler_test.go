@@ -0,0 +1,148 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/scheduler"
+)
+
+func TestScheduler_catchUp(t *testing.T) {
+	t.Run("run missed job on start", func(t *testing.T) {
+		// The job came due at the start of this minute, which is within the
+		// catch-up window, so it should run right away.
+		ran := make(chan struct{}, 10)
+		s, err := scheduler.New([]scheduler.Job{{
+			Name:     "every minute",
+			Schedule: "* * * * *",
+			CatchUp:  2 * time.Minute,
+			Run: func(context.Context) error {
+				ran <- struct{}{}
+				return nil
+			},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		go s.Run(ctx)
+
+		select {
+		case <-ran:
+		case <-ctx.Done():
+			t.Fatal("job did not catch up")
+		}
+
+		// The two missed runs in the window are coalesced into one.
+		select {
+		case <-ran:
+			if time.Now().Second() != 0 {
+				t.Error("job ran twice to catch up")
+			}
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+
+	t.Run("pass scheduled time to late run", func(t *testing.T) {
+		scheduled := make(chan time.Time, 10)
+		s, err := scheduler.New([]scheduler.Job{{
+			Name:     "hourly",
+			Schedule: "0 * * * *",
+			CatchUp:  time.Hour,
+			Run: func(ctx context.Context) error {
+				t, _ := scheduler.ScheduledTime(ctx)
+				scheduled <- t
+				return nil
+			},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		go s.Run(ctx)
+
+		select {
+		case got := <-scheduled:
+			// The run is for the top of the hour, not whenever it started.
+			assert.Equal(t, got, time.Now().UTC().Truncate(time.Hour))
+		case <-ctx.Done():
+			t.Fatal("job did not catch up")
+		}
+	})
+
+	t.Run("skip missed job that already finished", func(t *testing.T) {
+		// Runs less than a minute late aren't catching up.
+		if time.Now().Minute() == 0 {
+			t.Skip("too close to the top of the hour")
+		}
+
+		var checked time.Time
+		s, err := scheduler.New([]scheduler.Job{{
+			Name:     "hourly",
+			Schedule: "0 * * * *",
+			CatchUp:  time.Hour,
+			Finished: func(_ context.Context, scheduled time.Time) (bool, error) {
+				checked = scheduled
+				return true, nil
+			},
+			Run: func(context.Context) error {
+				t.Error("job should not have run")
+				return nil
+			},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		s.Run(ctx)
+
+		assert.Equal(t, checked, time.Now().UTC().Truncate(time.Hour))
+	})
+
+	t.Run("skip missed job without catch-up", func(t *testing.T) {
+		s, err := scheduler.New([]scheduler.Job{{
+			Name:     "yearly",
+			Schedule: "0 0 1 jan *",
+			Run: func(context.Context) error {
+				t.Error("job should not have run")
+				return nil
+			},
+		}})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		s.Run(ctx)
+	})
+}
+
+func TestNew_invalid(t *testing.T) {
+	_, err := scheduler.New([]scheduler.Job{{
+		Name:     "broken",
+		Schedule: "every day 04:00",
+		Run:      func(context.Context) error { return nil },
+	}})
+	assert.ErrorIs(t, err, scheduler.ErrInvalidExpression)
+
+	_, err = scheduler.New([]scheduler.Job{{
+		Name:     "no-op",
+		Schedule: "0 4 * * *",
+	}})
+	if err == nil {
+		t.Error("expected error for missing Run function")
+	}
+}
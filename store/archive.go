@@ -0,0 +1,229 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// ArchiveFormat identifies Pairing Bot archives in their header line.
+const ArchiveFormat = "pairing-bot-archive"
+
+// ArchiveVersion is the version of the archive format written by Export.
+// Import accepts archives up to this version.
+const ArchiveVersion = 1
+
+var ErrArchiveFormat = errors.New("invalid archive")
+
+// An ArchiveHeader is the first line of an archive.
+type ArchiveHeader struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exportedAt"`
+}
+
+// An ArchiveRecord is one document in an archive. Data holds the document
+// fields using the same names as in Firestore.
+type ArchiveRecord struct {
+	Collection string          `json:"collection"`
+	ID         string          `json:"id"`
+	Data       json.RawMessage `json:"data"`
+}
+
+// archivedCollections lists the collections in an archive, in export order.
+// Secrets are only exported on request.
+var archivedCollections = []string{"recursers", "pairings", "reviews", "onboarding", "outbox", "conversations", "jobRuns", "secrets"}
+
+// ArchiveClient exports and imports all of Pairing Bot's data.
+type ArchiveClient struct {
+	client *firestore.Client
+}
+
+func Archive(client *firestore.Client) *ArchiveClient {
+	return &ArchiveClient{client}
+}
+
+// Export writes a JSON Lines archive of the bot's data: a header line followed
+// by one line per document. Secrets are only included if includeSecrets is
+// true. It returns the number of documents exported from each collection.
+func (a *ArchiveClient) Export(ctx context.Context, w io.Writer, includeSecrets bool) (map[string]int, error) {
+	enc := json.NewEncoder(w)
+
+	header := ArchiveHeader{
+		Format:     ArchiveFormat,
+		Version:    ArchiveVersion,
+		ExportedAt: time.Now().UTC(),
+	}
+	if err := enc.Encode(header); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, collection := range archivedCollections {
+		if collection == "secrets" && !includeSecrets {
+			continue
+		}
+
+		records, err := a.exportCollection(ctx, collection)
+		if err != nil {
+			return nil, fmt.Errorf("export %s: %w", collection, err)
+		}
+
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return nil, err
+			}
+		}
+		counts[collection] = len(records)
+	}
+
+	return counts, nil
+}
+
+// exportCollection converts every document in the collection to a record.
+// The records hold each document's raw fields rather than what the bot's
+// types decode, so that fields from a different version of the bot survive
+// the round trip through Import, and a document that can't be read doesn't quietly go
+// missing from the archive.
+func (a *ArchiveClient) exportCollection(ctx context.Context, collection string) ([]ArchiveRecord, error) {
+	iter := a.client.Collection(collection).Documents(ctx)
+	defer iter.Stop()
+
+	var records []ArchiveRecord
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		data, err := json.Marshal(doc.Data())
+		if err != nil {
+			return nil, fmt.Errorf("encode %s/%s: %w", collection, doc.Ref.ID, err)
+		}
+		records = append(records, ArchiveRecord{Collection: collection, ID: doc.Ref.ID, Data: data})
+	}
+}
+
+// Import restores an archive written by Export. Each document is written with
+// its original ID, replacing any existing document, so importing the same
+// archive more than once has the same effect as importing it once. It returns
+// the number of documents imported into each collection.
+func (a *ArchiveClient) Import(ctx context.Context, r io.Reader) (map[string]int, error) {
+	scanner := bufio.NewScanner(r)
+	// Reviews can be long, so allow lines much longer than the default.
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: missing header", ErrArchiveFormat)
+	}
+
+	var header ArchiveHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrArchiveFormat, err)
+	}
+	if header.Format != ArchiveFormat {
+		return nil, fmt.Errorf("%w: unknown format %q", ErrArchiveFormat, header.Format)
+	}
+	if header.Version < 1 || header.Version > ArchiveVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d (wanted at most %d)", ErrArchiveFormat, header.Version, ArchiveVersion)
+	}
+
+	counts := make(map[string]int)
+	for line := 2; scanner.Scan(); line++ {
+		var record ArchiveRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return counts, fmt.Errorf("%w: line %d: %w", ErrArchiveFormat, line, err)
+		}
+
+		if err := a.importRecord(ctx, record); err != nil {
+			return counts, fmt.Errorf("line %d: %w", line, err)
+		}
+		counts[record.Collection]++
+	}
+
+	return counts, scanner.Err()
+}
+
+// archivedTimes lists the timestamp fields of each collection. JSON has no
+// timestamp type, so these are exported as strings and have to be parsed back
+// into timestamps on import.
+var archivedTimes = map[string][]string{
+	"recursers":     {"skipRequestedAt", "syncedAt", "unsubscribedAt"},
+	"onboarding":    {"introducedAt", "updatedAt"},
+	"conversations": {"askedAt", "expiresAt"},
+}
+
+// importRecord writes the record's raw fields, including any the bot's types
+// don't know about. Numbers are written as integers where they're whole (as
+// every number the bot stores is), and the collection's timestamp fields are
+// written as timestamps, so the values have the same Firestore types as when
+// they were exported.
+func (a *ArchiveClient) importRecord(ctx context.Context, record ArchiveRecord) error {
+	if record.ID == "" {
+		return fmt.Errorf("%w: %s record without an ID", ErrArchiveFormat, record.Collection)
+	}
+	if !slices.Contains(archivedCollections, record.Collection) {
+		return fmt.Errorf("%w: unknown collection %q", ErrArchiveFormat, record.Collection)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(record.Data))
+	dec.UseNumber()
+	var data map[string]any
+	if err := dec.Decode(&data); err != nil {
+		return fmt.Errorf("%w: %s/%s: %w", ErrArchiveFormat, record.Collection, record.ID, err)
+	}
+
+	for field, value := range data {
+		data[field] = importValue(value)
+	}
+	for _, field := range archivedTimes[record.Collection] {
+		s, ok := data[field].(string)
+		if !ok {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			return fmt.Errorf("%w: %s/%s: %s: %w", ErrArchiveFormat, record.Collection, record.ID, field, err)
+		}
+		data[field] = t
+	}
+
+	_, err := a.client.Collection(record.Collection).Doc(record.ID).Set(ctx, data)
+	return err
+}
+
+// importValue converts the numbers in a decoded JSON value back to the
+// Firestore types they were exported from.
+func importValue(value any) any {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		return f
+	case []any:
+		for i := range v {
+			v[i] = importValue(v[i])
+		}
+	case map[string]any:
+		for k := range v {
+			v[k] = importValue(v[k])
+		}
+	}
+	return value
+}
@@ -0,0 +1,239 @@
+package store_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func TestFirestoreArchiveClient(t *testing.T) {
+	t.Run("round-trip between projects", func(t *testing.T) {
+		ctx := context.Background()
+
+		src := pbtest.FirestoreClient(t, ctx)
+		dst := pbtest.FirestoreClient(t, ctx)
+
+		recurser := store.Recurser{
+			ID:            pbtest.RandInt64(t),
+			Name:          "Your Name",
+			Email:         "test@recurse.example.net",
+			Schedule:      store.NewSchedule([]string{"monday", "friday"}),
+			CurrentlyAtRC: true,
+		}
+		if err := store.Recursers(src).Set(ctx, recurser.ID, &recurser); err != nil {
+			t.Fatal(err)
+		}
+
+		pairing := store.Pairing{Value: 3, Timestamp: pbtest.RandInt64(t)}
+		if err := store.Pairings(src).SetNumPairings(ctx, pairing); err != nil {
+			t.Fatal(err)
+		}
+
+		review := store.Review{Content: "test review", Email: recurser.Email, Timestamp: pbtest.RandInt64(t)}
+		reviewID, err := store.Reviews(src).Insert(ctx, review)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := store.Onboarding(src).Start(ctx, recurser.ID, "Summer 2, 2024"); err != nil {
+			t.Fatal(err)
+		}
+
+		source := fmt.Sprintf("onboarding %d", recurser.ID)
+		if _, err := store.Outbox(src).Enqueue(ctx, source, []store.OutboxMessage{{
+			Recipients: []int64{recurser.ID},
+			Content:    "hello",
+		}}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := store.Secrets(src).Set(ctx, "zulip_api_key", "fake-key"); err != nil {
+			t.Fatal(err)
+		}
+
+		var archive bytes.Buffer
+		exported, err := store.Archive(src).Export(ctx, &archive, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, exported, map[string]int{
+			"recursers":     1,
+			"pairings":      1,
+			"reviews":       1,
+			"onboarding":    1,
+			"outbox":        1,
+			"conversations": 0,
+			"jobRuns":       0,
+		})
+
+		// Importing twice is the same as importing once.
+		for range 2 {
+			imported, err := store.Archive(dst).Import(ctx, bytes.NewReader(archive.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, imported, exported)
+		}
+
+		actual, err := store.Recursers(dst).Get(ctx, recurser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := recurser
+		expected.IsSubscribed = true
+		assert.Equal(t, actual, &expected)
+
+		reviews, err := store.Reviews(dst).GetAll(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Insert stamped the stored review with its ID, moderation status, and
+		// the current schema version.
+		review.ID = reviewID
+		review.Status = store.ReviewPending
+		review.SchemaVersion = store.CurrentSchemaVersion("reviews")
+		assert.Equal(t, reviews, []store.Review{review})
+
+		doc, err := dst.Collection("pairings").Doc(strconv.FormatInt(pairing.Timestamp, 10)).Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actualPairing store.Pairing
+		if err := doc.DataTo(&actualPairing); err != nil {
+			t.Fatal(err)
+		}
+		pairing.SchemaVersion = store.CurrentSchemaVersion("pairings")
+		assert.Equal(t, actualPairing, pairing)
+
+		progress, err := store.Onboarding(dst).Get(ctx, recurser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedProgress, err := store.Onboarding(src).Get(ctx, recurser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, progress, expectedProgress)
+
+		messages, err := store.Outbox(dst).ListFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectedMessages, err := store.Outbox(src).ListFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, messages, expectedMessages)
+
+		// Secrets were left out.
+		if _, err := store.Secrets(dst).Get(ctx, "zulip_api_key"); err == nil {
+			t.Error("secret should not have been exported")
+		}
+	})
+
+	t.Run("export raw documents", func(t *testing.T) {
+		ctx := context.Background()
+		client := pbtest.FirestoreClient(t, ctx)
+
+		// Neither an unknown field nor one of the wrong type keeps the
+		// document out of the archive.
+		id := strconv.FormatInt(pbtest.RandInt64(t), 10)
+		_, err := client.Collection("recursers").Doc(id).Set(ctx, map[string]any{
+			"name":        "Your Name",
+			"schedule":    "not a schedule",
+			"futureField": "kept",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var archive bytes.Buffer
+		exported, err := store.Archive(client).Export(ctx, &archive, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, exported["recursers"], 1)
+
+		lines := strings.Split(strings.TrimSpace(archive.String()), "\n")
+		var record store.ArchiveRecord
+		if err := json.Unmarshal([]byte(lines[1]), &record); err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, record.ID, id)
+		assert.Equal(t, string(record.Data), `{"futureField":"kept","name":"Your Name","schedule":"not a schedule"}`)
+	})
+
+	t.Run("round-trip raw documents", func(t *testing.T) {
+		ctx := context.Background()
+
+		src := pbtest.FirestoreClient(t, ctx)
+		dst := pbtest.FirestoreClient(t, ctx)
+
+		// Fields the bot's types don't know about, from an older or newer
+		// version of the bot, come back with the same values and types.
+		id := strconv.FormatInt(pbtest.RandInt64(t), 10)
+		original := map[string]any{
+			"id":          pbtest.RandInt64(t),
+			"name":        "Your Name",
+			"syncedAt":    time.Date(2024, 5, 20, 12, 30, 0, 123000, time.UTC),
+			"futureField": "kept",
+			"legacyCount": int64(3),
+			"legacyRatio": 0.5,
+			"legacyNested": map[string]any{
+				"ids": []any{int64(1), int64(2)},
+			},
+		}
+		if _, err := src.Collection("recursers").Doc(id).Set(ctx, original); err != nil {
+			t.Fatal(err)
+		}
+
+		var archive bytes.Buffer
+		if _, err := store.Archive(src).Export(ctx, &archive, false); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := store.Archive(dst).Import(ctx, &archive); err != nil {
+			t.Fatal(err)
+		}
+
+		expected, err := src.Collection("recursers").Doc(id).Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, err := dst.Collection("recursers").Doc(id).Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, actual.Data(), expected.Data())
+	})
+}
+
+func TestArchiveClient_Import_invalid(t *testing.T) {
+	// None of these get far enough to need a database.
+	archive := store.Archive(nil)
+	ctx := context.Background()
+
+	for name, input := range map[string]string{
+		"empty":          "",
+		"not JSON":       "hello\n",
+		"wrong format":   `{"format":"something-else","version":1}` + "\n",
+		"future version": `{"format":"pairing-bot-archive","version":99}` + "\n",
+		"unknown collection": `{"format":"pairing-bot-archive","version":1}` + "\n" +
+			`{"collection":"mystery","id":"1","data":{}}` + "\n",
+		"missing ID": `{"format":"pairing-bot-archive","version":1}` + "\n" +
+			`{"collection":"recursers","data":{}}` + "\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := archive.Import(ctx, strings.NewReader(input))
+			assert.ErrorIs(t, err, store.ErrArchiveFormat)
+		})
+	}
+}
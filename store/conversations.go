@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Conversation is a question the bot has asked a user and is waiting for them
+// to answer. Each user has at most one at a time: asking a new question
+// replaces the old one.
+type Conversation struct {
+	ID int64 `firestore:"id" json:"id"`
+	// Flow is the command that asked the question, which the answer will
+	// finish.
+	Flow      string    `firestore:"flow" json:"flow"`
+	AskedAt   time.Time `firestore:"askedAt" json:"askedAt"`
+	ExpiresAt time.Time `firestore:"expiresAt" json:"expiresAt"`
+}
+
+// Expired returns whether the question has gone unanswered for too long to
+// still be waiting on.
+func (c *Conversation) Expired(now time.Time) bool {
+	return !now.Before(c.ExpiresAt)
+}
+
+// ConversationsClient keeps track of the questions waiting on answers.
+type ConversationsClient struct {
+	client *firestore.Client
+}
+
+func Conversations(client *firestore.Client) *ConversationsClient {
+	return &ConversationsClient{client}
+}
+
+// Ask records that the user has been asked a question for the flow, which
+// they have until timeout to answer.
+func (c *ConversationsClient) Ask(ctx context.Context, userID int64, flow string, timeout time.Duration) error {
+	now := time.Now()
+	conv := Conversation{
+		ID:        userID,
+		Flow:      flow,
+		AskedAt:   now,
+		ExpiresAt: now.Add(timeout),
+	}
+
+	_, err := c.doc(userID).Set(ctx, conv)
+	return err
+}
+
+// Get returns the question waiting on the user's answer, or nil if there
+// isn't one. Questions that have expired are still returned, so that late
+// answers can be recognized.
+func (c *ConversationsClient) Get(ctx context.Context, userID int64) (*Conversation, error) {
+	doc, err := c.doc(userID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var conv Conversation
+	if err := doc.DataTo(&conv); err != nil {
+		return nil, fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+	}
+	return &conv, nil
+}
+
+// Clear stops waiting for the user to answer. This succeeds even if there was
+// no question waiting.
+func (c *ConversationsClient) Clear(ctx context.Context, userID int64) error {
+	_, err := c.doc(userID).Delete(ctx)
+	return err
+}
+
+func (c *ConversationsClient) doc(userID int64) *firestore.DocumentRef {
+	return c.client.Collection("conversations").Doc(strconv.FormatInt(userID, 10))
+}
@@ -0,0 +1,48 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func TestFirestoreConversationsClient(t *testing.T) {
+	ctx := context.Background()
+
+	client := pbtest.FirestoreClient(t, ctx)
+	conversations := store.Conversations(client)
+
+	t.Run("nothing asked", func(t *testing.T) {
+		conv, err := conversations.Get(ctx, pbtest.RandInt64(t))
+		assert.NoError(t, err)
+		if conv != nil {
+			t.Errorf("expected no conversation, got %+v", conv)
+		}
+	})
+
+	t.Run("ask, replace, and clear", func(t *testing.T) {
+		id := pbtest.RandInt64(t)
+
+		assert.NoError(t, conversations.Ask(ctx, id, "schedule", time.Hour))
+		assert.NoError(t, conversations.Ask(ctx, id, "unsubscribe", time.Hour))
+
+		conv, err := conversations.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, conv.Flow, "unsubscribe")
+		assert.Equal(t, conv.Expired(time.Now()), false)
+		assert.Equal(t, conv.Expired(time.Now().Add(2*time.Hour)), true)
+
+		assert.NoError(t, conversations.Clear(ctx, id))
+		assert.NoError(t, conversations.Clear(ctx, id))
+
+		conv, err = conversations.Get(ctx, id)
+		assert.NoError(t, err)
+		if conv != nil {
+			t.Errorf("expected no conversation after clearing, got %+v", conv)
+		}
+	})
+}
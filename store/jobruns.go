@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Job run states.
+const (
+	JobRunning   = "running"
+	JobSucceeded = "succeeded"
+	JobFailed    = "failed"
+)
+
+// A JobRun records one scheduled run of a cron job. Retries of the same
+// scheduled run share a record, with Attempt counting up.
+type JobRun struct {
+	// ID is the Firestore document ID. It is not stored in the document.
+	ID string `firestore:"-" json:"id"`
+
+	Job       string `firestore:"job" json:"job"`
+	Scheduled int64  `firestore:"scheduled" json:"scheduled"`
+	Attempt   int    `firestore:"attempt" json:"attempt"`
+
+	Status      string `firestore:"status" json:"status"`
+	Error       string `firestore:"error" json:"error"`
+	TriggeredBy string `firestore:"triggeredBy" json:"triggeredBy"`
+
+	StartedAt      int64 `firestore:"startedAt" json:"startedAt"`
+	EndedAt        int64 `firestore:"endedAt" json:"endedAt"`
+	LeaseExpiresAt int64 `firestore:"leaseExpiresAt" json:"leaseExpiresAt"`
+}
+
+// JobRunsClient manages the history and leases of cron job runs.
+type JobRunsClient struct {
+	client *firestore.Client
+}
+
+func JobRuns(client *firestore.Client) *JobRunsClient {
+	return &JobRunsClient{client}
+}
+
+// jobRunID returns the document ID shared by all attempts of a scheduled run.
+func jobRunID(job string, scheduled time.Time) string {
+	return fmt.Sprintf("%s@%s", job, scheduled.UTC().Format(time.RFC3339))
+}
+
+// Acquire takes the lease on the job's scheduled run. It returns false if the
+// run already succeeded or another attempt still holds an unexpired lease. A
+// failed or abandoned (lease-expired) run can be acquired again.
+//
+// triggeredBy describes who started this attempt, for the run history.
+func (j *JobRunsClient) Acquire(ctx context.Context, job string, scheduled time.Time, lease time.Duration, triggeredBy string) (*JobRun, bool, error) {
+	ref := j.client.Collection("jobRuns").Doc(jobRunID(job, scheduled))
+
+	var run JobRun
+	acquired := false
+
+	err := j.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Transactions may be retried, so reset any state from earlier tries.
+		run = JobRun{Job: job, Scheduled: scheduled.Unix()}
+		acquired = false
+
+		now := time.Now()
+
+		doc, err := tx.Get(ref)
+		if err != nil && status.Code(err) != codes.NotFound {
+			return err
+		}
+
+		if doc.Exists() {
+			if err := doc.DataTo(&run); err != nil {
+				return fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+			}
+
+			switch {
+			case run.Status == JobSucceeded:
+				return nil
+			case run.Status == JobRunning && now.Unix() < run.LeaseExpiresAt:
+				return nil
+			}
+		}
+
+		run.Attempt++
+		run.Status = JobRunning
+		run.Error = ""
+		run.TriggeredBy = triggeredBy
+		run.StartedAt = now.Unix()
+		run.EndedAt = 0
+		run.LeaseExpiresAt = now.Add(lease).Unix()
+
+		acquired = true
+		return tx.Set(ref, run)
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	run.ID = ref.ID
+	return &run, acquired, nil
+}
+
+// Finish records the outcome of the run and releases its lease. If another
+// attempt has taken over the run in the meantime, the record is left alone.
+func (j *JobRunsClient) Finish(ctx context.Context, run *JobRun, jobErr error) error {
+	ref := j.client.Collection("jobRuns").Doc(run.ID)
+
+	run.EndedAt = time.Now().Unix()
+	run.LeaseExpiresAt = 0
+	if jobErr != nil {
+		run.Status = JobFailed
+		run.Error = jobErr.Error()
+	} else {
+		run.Status = JobSucceeded
+		run.Error = ""
+	}
+
+	return j.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		var current JobRun
+		if err := doc.DataTo(&current); err != nil {
+			return fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+		}
+
+		if current.Attempt != run.Attempt {
+			return fmt.Errorf("job run %q was taken over by attempt %d", run.ID, current.Attempt)
+		}
+
+		return tx.Set(ref, run)
+	})
+}
+
+// Succeeded returns whether the job's scheduled run has already finished
+// successfully.
+func (j *JobRunsClient) Succeeded(ctx context.Context, job string, scheduled time.Time) (bool, error) {
+	doc, err := j.client.Collection("jobRuns").Doc(jobRunID(job, scheduled)).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	var run JobRun
+	if err := doc.DataTo(&run); err != nil {
+		return false, fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+	}
+	return run.Status == JobSucceeded, nil
+}
+
+// ListRecent returns the n most recently started job runs, newest first.
+func (j *JobRunsClient) ListRecent(ctx context.Context, n int) ([]JobRun, error) {
+	iter := j.client.
+		Collection("jobRuns").
+		OrderBy("startedAt", firestore.Desc).
+		Limit(n).
+		Documents(ctx)
+	return fetchAll[JobRun](iter)
+}
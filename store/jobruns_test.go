@@ -0,0 +1,91 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func TestFirestoreJobRunsClient(t *testing.T) {
+	t.Run("one run per slot", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		runs := store.JobRuns(client)
+
+		job := fmt.Sprintf("job-%d", pbtest.RandInt64(t))
+		scheduled := time.Now().Truncate(24 * time.Hour)
+
+		run, acquired, err := runs.Acquire(ctx, job, scheduled, time.Hour, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, acquired, true)
+		assert.Equal(t, run.Attempt, 1)
+		assert.Equal(t, run.Status, store.JobRunning)
+
+		// An overlapping run can't take the lease.
+		_, acquired, err = runs.Acquire(ctx, job, scheduled, time.Hour, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, acquired, false)
+
+		if err := runs.Finish(ctx, run, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		// Neither can a later run once the first one succeeded.
+		done, acquired, err := runs.Acquire(ctx, job, scheduled, time.Hour, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, acquired, false)
+		assert.Equal(t, done.Status, store.JobSucceeded)
+	})
+
+	t.Run("retry after failure or expired lease", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		runs := store.JobRuns(client)
+
+		job := fmt.Sprintf("job-%d", pbtest.RandInt64(t))
+		scheduled := time.Now().Truncate(24 * time.Hour)
+
+		first, _, err := runs.Acquire(ctx, job, scheduled, time.Hour, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := runs.Finish(ctx, first, errors.New("oops")); err != nil {
+			t.Fatal(err)
+		}
+
+		// A failed run can be retried. Use a lease that's already expired.
+		second, acquired, err := runs.Acquire(ctx, job, scheduled, -time.Second, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, acquired, true)
+		assert.Equal(t, second.Attempt, 2)
+
+		// The second attempt "crashed", so a third may take over.
+		third, acquired, err := runs.Acquire(ctx, job, scheduled, time.Hour, "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, acquired, true)
+		assert.Equal(t, third.Attempt, 3)
+
+		// The second attempt can't overwrite the third's record.
+		if err := runs.Finish(ctx, second, nil); err == nil {
+			t.Error("expected error finishing a run that was taken over")
+		}
+	})
+}
@@ -0,0 +1,111 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Onboarding steps, in order. Each one is what the bot is waiting for the
+// user to do next.
+const (
+	OnboardingSubscribe = "subscribe"
+	OnboardingSchedule  = "schedule"
+	OnboardingInterests = "interests"
+	OnboardingDone      = "done"
+)
+
+// OnboardingForgotten marks someone who asked to be forgotten. It's all that's
+// kept about them, so that they aren't introduced to Pairing Bot again.
+const OnboardingForgotten = "forgotten"
+
+// OnboardingProgress tracks the introduction DM sent to someone new at RC, and
+// how far they've gotten through setting up Pairing Bot since.
+type OnboardingProgress struct {
+	ID int64 `firestore:"id" json:"id"`
+	// Batch is the name of the batch the user was starting.
+	Batch        string    `firestore:"batch" json:"batch"`
+	IntroducedAt time.Time `firestore:"introducedAt" json:"introducedAt"`
+
+	Step string `firestore:"step" json:"step"`
+	// Reminded is set once the user has been reminded to subscribe, so that
+	// they aren't reminded again.
+	Reminded  bool      `firestore:"reminded" json:"reminded"`
+	UpdatedAt time.Time `firestore:"updatedAt" json:"updatedAt"`
+}
+
+// OnboardingClient manages onboarding progress for people new to RC.
+type OnboardingClient struct {
+	client *firestore.Client
+}
+
+func Onboarding(client *firestore.Client) *OnboardingClient {
+	return &OnboardingClient{client}
+}
+
+// Start records that the user is being introduced to Pairing Bot. It returns
+// false if they've already been introduced, so that nobody is introduced
+// twice.
+func (o *OnboardingClient) Start(ctx context.Context, userID int64, batch string) (bool, error) {
+	now := time.Now()
+	progress := OnboardingProgress{
+		ID:           userID,
+		Batch:        batch,
+		IntroducedAt: now,
+		Step:         OnboardingSubscribe,
+		UpdatedAt:    now,
+	}
+
+	_, err := o.doc(userID).Create(ctx, progress)
+	if status.Code(err) == codes.AlreadyExists {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Get returns the user's onboarding progress, or nil if they were never
+// introduced.
+func (o *OnboardingClient) Get(ctx context.Context, userID int64) (*OnboardingProgress, error) {
+	doc, err := o.doc(userID).Get(ctx)
+	if status.Code(err) == codes.NotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var progress OnboardingProgress
+	if err := doc.DataTo(&progress); err != nil {
+		return nil, fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+	}
+	return &progress, nil
+}
+
+// Advance moves the user on to the given step.
+func (o *OnboardingClient) Advance(ctx context.Context, userID int64, step string) error {
+	return o.update(ctx, userID,
+		firestore.Update{Path: "step", Value: step},
+		firestore.Update{Path: "updatedAt", Value: time.Now()},
+	)
+}
+
+// SetReminded records that the user has been reminded to subscribe.
+func (o *OnboardingClient) SetReminded(ctx context.Context, userID int64) error {
+	return o.update(ctx, userID,
+		firestore.Update{Path: "reminded", Value: true},
+		firestore.Update{Path: "updatedAt", Value: time.Now()},
+	)
+}
+
+func (o *OnboardingClient) update(ctx context.Context, userID int64, updates ...firestore.Update) error {
+	_, err := o.doc(userID).Update(ctx, updates, firestore.Exists)
+	return err
+}
+
+func (o *OnboardingClient) doc(userID int64) *firestore.DocumentRef {
+	return o.client.Collection("onboarding").Doc(strconv.FormatInt(userID, 10))
+}
@@ -0,0 +1,57 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func TestFirestoreOnboardingClient(t *testing.T) {
+	ctx := context.Background()
+
+	client := pbtest.FirestoreClient(t, ctx)
+	onboarding := store.Onboarding(client)
+
+	t.Run("never introduced", func(t *testing.T) {
+		progress, err := onboarding.Get(ctx, pbtest.RandInt64(t))
+		assert.NoError(t, err)
+		if progress != nil {
+			t.Errorf("expected no progress, got %+v", progress)
+		}
+	})
+
+	t.Run("introduced once", func(t *testing.T) {
+		id := pbtest.RandInt64(t)
+
+		started, err := onboarding.Start(ctx, id, "Fall 1, 2024")
+		assert.NoError(t, err)
+		assert.Equal(t, started, true)
+
+		started, err = onboarding.Start(ctx, id, "Fall 1, 2024")
+		assert.NoError(t, err)
+		assert.Equal(t, started, false)
+
+		progress, err := onboarding.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, progress.Step, store.OnboardingSubscribe)
+		assert.Equal(t, progress.Batch, "Fall 1, 2024")
+	})
+
+	t.Run("progress", func(t *testing.T) {
+		id := pbtest.RandInt64(t)
+
+		_, err := onboarding.Start(ctx, id, "Fall 1, 2024")
+		assert.NoError(t, err)
+
+		assert.NoError(t, onboarding.SetReminded(ctx, id))
+		assert.NoError(t, onboarding.Advance(ctx, id, store.OnboardingSchedule))
+
+		progress, err := onboarding.Get(ctx, id)
+		assert.NoError(t, err)
+		assert.Equal(t, progress.Step, store.OnboardingSchedule)
+		assert.Equal(t, progress.Reminded, true)
+	})
+}
@@ -0,0 +1,267 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// Outbox message delivery states.
+const (
+	// OutboxPending messages are waiting to be (re-)sent.
+	OutboxPending = "pending"
+	// OutboxDelivered messages were accepted by Zulip.
+	OutboxDelivered = "delivered"
+	// OutboxFailed messages ran out of attempts or expired before they could
+	// be delivered. They're kept for the maintainers to look into.
+	OutboxFailed = "failed"
+)
+
+// An OutboxMessage is a chat message that Pairing Bot has committed to
+// sending. Exactly one of Recipients (a direct message) or Stream (a topic
+// post) is set.
+type OutboxMessage struct {
+	// ID is the Firestore document ID. It is not stored in the document.
+	ID string `firestore:"-" json:"id"`
+
+	// Source identifies the job run that queued this message, e.g.,
+	// "match 2024-05-20". Each source can only be enqueued once.
+	Source string `firestore:"source" json:"source"`
+
+	Recipients []int64 `firestore:"recipients" json:"recipients"`
+	Stream     string  `firestore:"stream" json:"stream"`
+	Topic      string  `firestore:"topic" json:"topic"`
+	Content    string  `firestore:"content" json:"content"`
+
+	Status    string `firestore:"status" json:"status"`
+	Attempts  int    `firestore:"attempts" json:"attempts"`
+	LastError string `firestore:"lastError" json:"lastError"`
+
+	// ClaimedUntil is when the current delivery attempt's claim on the
+	// message runs out, or zero if nobody is delivering it.
+	ClaimedUntil int64 `firestore:"claimedUntil" json:"claimedUntil"`
+
+	CreatedAt   int64 `firestore:"createdAt" json:"createdAt"`
+	ExpiresAt   int64 `firestore:"expiresAt" json:"expiresAt"`
+	DeliveredAt int64 `firestore:"deliveredAt" json:"deliveredAt"`
+}
+
+// Expired returns whether the message is no longer worth sending.
+func (m OutboxMessage) Expired(now time.Time) bool {
+	return m.ExpiresAt != 0 && now.Unix() >= m.ExpiresAt
+}
+
+// Claimed returns whether a delivery attempt is in progress.
+func (m OutboxMessage) Claimed(now time.Time) bool {
+	return now.Unix() < m.ClaimedUntil
+}
+
+// OutboxClient manages messages waiting to be sent to Zulip.
+type OutboxClient struct {
+	client *firestore.Client
+}
+
+func Outbox(client *firestore.Client) *OutboxClient {
+	return &OutboxClient{client}
+}
+
+// OutboxRetention is how long delivered and failed messages are kept before
+// Prune deletes them. Enqueue relies on a source's messages still being there
+// to keep from queueing the source again, so this must be longer than any job
+// keeps trying to queue the same source. The longest is a newcomer's
+// introduction, which is retried for their first two weeks at RC.
+const OutboxRetention = 30 * 24 * time.Hour
+
+// Enqueue atomically records all of the messages as pending under the given
+// source. If anything was already queued for the source, nothing is written
+// and this returns false. This makes it safe to re-run a job: only the first
+// run's messages are ever queued, at least until they're pruned after
+// OutboxRetention.
+func (o *OutboxClient) Enqueue(ctx context.Context, source string, messages []OutboxMessage) (bool, error) {
+	col := o.client.Collection("outbox")
+	now := time.Now().Unix()
+
+	queued := false
+	err := o.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Transactions may be retried, so reset any state from earlier tries.
+		queued = false
+
+		existing := tx.Documents(col.Where("source", "==", source).Limit(1))
+		_, err := existing.Next()
+		existing.Stop()
+		if err == nil {
+			return nil
+		} else if err != iterator.Done {
+			return err
+		}
+
+		for _, msg := range messages {
+			msg.Source = source
+			msg.Status = OutboxPending
+			if msg.CreatedAt == 0 {
+				msg.CreatedAt = now
+			}
+
+			if err := tx.Create(col.NewDoc(), msg); err != nil {
+				return err
+			}
+		}
+
+		queued = true
+		return nil
+	})
+	return queued, err
+}
+
+// ListPending returns all messages that have not been delivered yet.
+func (o *OutboxClient) ListPending(ctx context.Context) ([]OutboxMessage, error) {
+	iter := o.client.
+		Collection("outbox").
+		Where("status", "==", OutboxPending).
+		Documents(ctx)
+	return fetchOutbox(iter)
+}
+
+// ListPendingFrom returns the messages from one source that have not been
+// delivered yet.
+func (o *OutboxClient) ListPendingFrom(ctx context.Context, source string) ([]OutboxMessage, error) {
+	iter := o.client.
+		Collection("outbox").
+		Where("source", "==", source).
+		Where("status", "==", OutboxPending).
+		Documents(ctx)
+	return fetchOutbox(iter)
+}
+
+// ListFrom returns all of the messages from one source, whether or not they
+// have been delivered.
+func (o *OutboxClient) ListFrom(ctx context.Context, source string) ([]OutboxMessage, error) {
+	iter := o.client.
+		Collection("outbox").
+		Where("source", "==", source).
+		Documents(ctx)
+	return fetchOutbox(iter)
+}
+
+// ListPrunable returns the delivered and failed messages created before
+// cutoff. Pending messages are never pruned, however old they are, so that
+// they're still delivered or marked as expired.
+func (o *OutboxClient) ListPrunable(ctx context.Context, cutoff time.Time) ([]OutboxMessage, error) {
+	iter := o.client.
+		Collection("outbox").
+		Where("createdAt", "<", cutoff.Unix()).
+		Documents(ctx)
+	messages, err := fetchOutbox(iter)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(messages, func(msg OutboxMessage) bool { return msg.Status == OutboxPending }), nil
+}
+
+// Prune deletes the messages that ListPrunable returns, since they record who
+// was paired with whom and aren't needed once they're settled. It returns the
+// number of messages deleted.
+func (o *OutboxClient) Prune(ctx context.Context, cutoff time.Time) (int, error) {
+	messages, err := o.ListPrunable(ctx, cutoff)
+	if err != nil {
+		return 0, err
+	}
+
+	for i, msg := range messages {
+		if _, err := o.client.Collection("outbox").Doc(msg.ID).Delete(ctx); err != nil {
+			return i, err
+		}
+	}
+	return len(messages), nil
+}
+
+// Claim reserves a pending message for one delivery attempt until the given
+// deadline, so that jobs running at the same time don't both send it. It
+// returns the message as currently stored, and false if the message is no
+// longer pending or another attempt's claim hasn't run out yet.
+//
+// Claims are released when the attempt's outcome is recorded. A claim left
+// behind by a crash runs out on its own, so the message is retried after the
+// deadline.
+func (o *OutboxClient) Claim(ctx context.Context, id string, until time.Time) (OutboxMessage, bool, error) {
+	ref := o.client.Collection("outbox").Doc(id)
+
+	var msg OutboxMessage
+	claimed := false
+	err := o.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Transactions may be retried, so reset any state from earlier tries.
+		claimed = false
+
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		msg = OutboxMessage{}
+		if err := decode(doc, &msg); err != nil {
+			return fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+		}
+		msg.ID = id
+
+		if msg.Status != OutboxPending || msg.Claimed(time.Now()) {
+			return nil
+		}
+
+		claimed = true
+		msg.ClaimedUntil = until.Unix()
+		return tx.Update(ref, []firestore.Update{{Path: "claimedUntil", Value: msg.ClaimedUntil}})
+	})
+	return msg, claimed, err
+}
+
+// MarkDelivered records that the message was sent successfully.
+func (o *OutboxClient) MarkDelivered(ctx context.Context, id string, at time.Time) error {
+	_, err := o.client.Collection("outbox").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "status", Value: OutboxDelivered},
+		{Path: "attempts", Value: firestore.Increment(1)},
+		{Path: "deliveredAt", Value: at.Unix()},
+		{Path: "claimedUntil", Value: 0},
+	})
+	return err
+}
+
+// MarkAttemptFailed records an unsuccessful delivery attempt. The message
+// stays pending so that it will be retried later.
+func (o *OutboxClient) MarkAttemptFailed(ctx context.Context, id string, sendErr error) error {
+	_, err := o.client.Collection("outbox").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "attempts", Value: firestore.Increment(1)},
+		{Path: "lastError", Value: sendErr.Error()},
+		{Path: "claimedUntil", Value: 0},
+	})
+	return err
+}
+
+// MarkFailed gives up on delivering the message.
+func (o *OutboxClient) MarkFailed(ctx context.Context, id string, reason string) error {
+	_, err := o.client.Collection("outbox").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "status", Value: OutboxFailed},
+		{Path: "lastError", Value: reason},
+		{Path: "claimedUntil", Value: 0},
+	})
+	return err
+}
+
+// fetchOutbox is like fetchAll, but it also fills in each message's ID.
+func fetchOutbox(iter *firestore.DocumentIterator) ([]OutboxMessage, error) {
+	docs, err := fetchDocs[OutboxMessage](iter)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []OutboxMessage
+	for _, doc := range docs {
+		doc.Data.ID = doc.ID
+		all = append(all, doc.Data)
+	}
+	return all, nil
+}
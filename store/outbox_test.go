@@ -0,0 +1,215 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func TestFirestoreOutboxClient(t *testing.T) {
+	t.Run("enqueue once per source", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		outbox := store.Outbox(client)
+
+		source := fmt.Sprintf("match %d", pbtest.RandInt64(t))
+
+		first := []store.OutboxMessage{
+			{Recipients: []int64{1, 2}, Content: "first"},
+			{Recipients: []int64{3}, Content: "odd one out"},
+		}
+		queued, err := outbox.Enqueue(ctx, source, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, queued, true)
+
+		// A second run for the same source must not queue anything.
+		second := []store.OutboxMessage{
+			{Recipients: []int64{1, 3}, Content: "second"},
+		}
+		queued, err = outbox.Enqueue(ctx, source, second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, queued, false)
+
+		pending, err := outbox.ListPendingFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var contents []string
+		for _, msg := range pending {
+			assert.Equal(t, msg.Source, source)
+			assert.Equal(t, msg.Status, store.OutboxPending)
+			contents = append(contents, msg.Content)
+		}
+		assert.Equal(t, len(contents), 2)
+		for _, c := range contents {
+			if c != "first" && c != "odd one out" {
+				t.Errorf("unexpected message content %q", c)
+			}
+		}
+	})
+
+	t.Run("record delivery attempts", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		outbox := store.Outbox(client)
+
+		source := fmt.Sprintf("match %d", pbtest.RandInt64(t))
+
+		_, err := outbox.Enqueue(ctx, source, []store.OutboxMessage{
+			{Recipients: []int64{1, 2}, Content: "delivered"},
+			{Recipients: []int64{3, 4}, Content: "retried"},
+			{Recipients: []int64{5, 6}, Content: "abandoned"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := outbox.ListPendingFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, msg := range pending {
+			switch msg.Content {
+			case "delivered":
+				err = outbox.MarkDelivered(ctx, msg.ID, time.Now())
+			case "retried":
+				err = outbox.MarkAttemptFailed(ctx, msg.ID, errors.New("try again"))
+			case "abandoned":
+				err = outbox.MarkFailed(ctx, msg.ID, "gave up")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		// Only the retried message is still waiting to be sent.
+		pending, err = outbox.ListPendingFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if assert.Equal(t, len(pending), 1) {
+			assert.Equal(t, pending[0].Content, "retried")
+			assert.Equal(t, pending[0].Attempts, 1)
+			assert.Equal(t, pending[0].LastError, "try again")
+		}
+	})
+
+	t.Run("one claim at a time", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		outbox := store.Outbox(client)
+
+		source := fmt.Sprintf("match %d", pbtest.RandInt64(t))
+
+		_, err := outbox.Enqueue(ctx, source, []store.OutboxMessage{
+			{Recipients: []int64{1, 2}, Content: "claimed"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		pending, err := outbox.ListPendingFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		id := pending[0].ID
+
+		msg, claimed, err := outbox.Claim(ctx, id, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, claimed, true)
+		assert.Equal(t, msg.Content, "claimed")
+
+		// Another job can't deliver it at the same time...
+		_, claimed, err = outbox.Claim(ctx, id, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, claimed, false)
+
+		// ... but can retry it once the first attempt is over.
+		assert.NoError(t, outbox.MarkAttemptFailed(ctx, id, errors.New("try again")))
+		_, claimed, err = outbox.Claim(ctx, id, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, claimed, true)
+
+		// Delivered messages are never claimed again.
+		assert.NoError(t, outbox.MarkDelivered(ctx, id, time.Now()))
+		_, claimed, err = outbox.Claim(ctx, id, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, claimed, false)
+
+		all, err := outbox.ListFrom(ctx, source)
+		assert.NoError(t, err)
+		assert.Equal(t, len(all), 1)
+	})
+
+	t.Run("prune settled messages", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		outbox := store.Outbox(client)
+
+		now := time.Now()
+		old := now.Add(-store.OutboxRetention - time.Hour).Unix()
+		source := fmt.Sprintf("match %d", pbtest.RandInt64(t))
+		_, err := outbox.Enqueue(ctx, source, []store.OutboxMessage{
+			{Recipients: []int64{1, 2}, Content: "delivered", CreatedAt: old},
+			{Recipients: []int64{3, 4}, Content: "failed", CreatedAt: old},
+			{Recipients: []int64{5}, Content: "pending", CreatedAt: old},
+			{Recipients: []int64{6, 7}, Content: "recent", CreatedAt: now.Unix()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		messages, err := outbox.ListFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, msg := range messages {
+			switch msg.Content {
+			case "delivered", "recent":
+				err = outbox.MarkDelivered(ctx, msg.ID, now)
+			case "failed":
+				err = outbox.MarkFailed(ctx, msg.ID, "expired")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		pruned, err := outbox.Prune(ctx, now.Add(-store.OutboxRetention))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, pruned, 2)
+
+		// Old pending messages are kept until they're delivered or expire,
+		// and recent ones until they're old enough.
+		messages, err = outbox.ListFrom(ctx, source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var contents []string
+		for _, msg := range messages {
+			contents = append(contents, msg.Content)
+		}
+		slices.Sort(contents)
+		assert.Equal(t, contents, []string{"pending", "recent"})
+	})
+}
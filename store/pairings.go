@@ -11,8 +11,13 @@ import (
 )
 
 type Pairing struct {
-	Value     int   `firestore:"value"`
-	Timestamp int64 `firestore:"timestamp"`
+	Value     int   `firestore:"value" json:"value"`
+	Timestamp int64 `firestore:"timestamp" json:"timestamp"`
+
+	// Alumni is how many of the pairs included at least one alum.
+	Alumni int `firestore:"alumni" json:"alumni"`
+
+	SchemaVersion int `firestore:"schemaVersion" json:"schemaVersion"`
 }
 
 // PairingsClient manages pairing (matching) result records.
@@ -26,13 +31,21 @@ func Pairings(client *firestore.Client) *PairingsClient {
 
 func (p *PairingsClient) SetNumPairings(ctx context.Context, pairing Pairing) error {
 	timestampAsString := strconv.FormatInt(pairing.Timestamp, 10)
+	pairing.SchemaVersion = CurrentSchemaVersion("pairings")
 
 	_, err := p.client.Collection("pairings").Doc(timestampAsString).Set(ctx, pairing)
 	return err
 }
 
 func (p *PairingsClient) GetTotalPairingsDuringLastWeek(ctx context.Context) (int, error) {
-	totalPairings := 0
+	totals, err := p.GetTotalsDuringLastWeek(ctx)
+	return totals.Value, err
+}
+
+// GetTotalsDuringLastWeek adds up the daily records from the last week. The
+// result's Timestamp is unset.
+func (p *PairingsClient) GetTotalsDuringLastWeek(ctx context.Context) (Pairing, error) {
+	var totals Pairing
 
 	timestampSevenDaysAgo := time.Now().Add(-7 * 24 * time.Hour).Unix()
 
@@ -43,19 +56,31 @@ func (p *PairingsClient) GetTotalPairingsDuringLastWeek(ctx context.Context) (in
 			break
 		}
 		if err != nil {
-			return 0, err
+			return Pairing{}, err
 		}
 
 		var pairing Pairing
-		if err = doc.DataTo(&pairing); err != nil {
+		if err = decode(doc, &pairing); err != nil {
 			log.Printf("Skipping %q: %s", doc.Ref.Path, err)
 			continue
 		}
 
 		log.Println("The timestamp is: ", pairing.Timestamp)
 
-		totalPairings += pairing.Value
+		totals.Value += pairing.Value
+		totals.Alumni += pairing.Alumni
 	}
 
-	return totalPairings, nil
+	return totals, nil
+}
+
+// ListSince returns the daily pairing records after the given time, oldest
+// first.
+func (p *PairingsClient) ListSince(ctx context.Context, since time.Time) ([]Pairing, error) {
+	iter := p.client.
+		Collection("pairings").
+		Where("timestamp", ">", since.Unix()).
+		OrderBy("timestamp", firestore.Asc).
+		Documents(ctx)
+	return fetchAll[Pairing](iter)
 }
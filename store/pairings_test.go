@@ -21,6 +21,7 @@ func TestFirestorePairingsClient(t *testing.T) {
 		for i := 6; i >= 0; i-- {
 			err := pairings.SetNumPairings(ctx, store.Pairing{
 				Value:     5,
+				Alumni:    i % 2,
 				Timestamp: time.Now().Add(-time.Duration(i) * 24 * time.Hour).Unix(),
 			})
 			if err != nil {
@@ -36,5 +37,13 @@ func TestFirestorePairingsClient(t *testing.T) {
 		}
 
 		assert.Equal(t, actual, expected)
+
+		totals, err := pairings.GetTotalsDuringLastWeek(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, totals.Value, expected)
+		assert.Equal(t, totals.Alumni, 3)
 	})
 }
@@ -0,0 +1,299 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UserData is everything Pairing Bot stores about one person.
+//
+// The pairings collection isn't included: it only holds daily totals, which
+// can't be traced back to anyone.
+type UserData struct {
+	// Recurser is the subscription record, or nil if there isn't one.
+	Recurser *Recurser `json:"recurser"`
+	// Reviews are the reviews written by the person, or from any of their
+	// emails.
+	Reviews []Review `json:"reviews"`
+	// Messages are the match notifications and other direct messages sent
+	// to the person, which record who they were paired with.
+	Messages []OutboxMessage `json:"messages"`
+	// Onboarding is the person's progress since they were introduced to
+	// Pairing Bot, or nil if they never were.
+	Onboarding *OnboardingProgress `json:"onboarding"`
+	// Conversation is the question waiting on the person's answer, or nil if
+	// there isn't one.
+	Conversation *Conversation `json:"conversation"`
+}
+
+// Empty returns whether nothing is stored about the person.
+func (d *UserData) Empty() bool {
+	return d.Recurser == nil && len(d.Reviews) == 0 && len(d.Messages) == 0 && d.Onboarding == nil && d.Conversation == nil
+}
+
+// PrivacyClient finds and erases everything stored about a person, to answer
+// privacy requests.
+type PrivacyClient struct {
+	client *firestore.Client
+}
+
+func Privacy(client *firestore.Client) *PrivacyClient {
+	return &PrivacyClient{client}
+}
+
+// Collect gathers everything stored about the user with the given Zulip ID.
+// Older reviews are only linked to people by email, so this also matches
+// reviews from the given email addresses and the one on the user's record.
+func (p *PrivacyClient) Collect(ctx context.Context, userID int64, emails ...string) (*UserData, error) {
+	var data *UserData
+	err := p.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		docs, err := p.find(tx, userID, emails)
+		if err != nil {
+			return err
+		}
+		data, err = docs.decode()
+		return err
+	}, firestore.ReadOnly)
+	return data, err
+}
+
+// Forget permanently deletes everything stored about the user, matching
+// documents the same way as Collect. It returns the number of documents
+// deleted or redacted in each collection. Messages shared with other people
+// are redacted rather than deleted (see forgetRecipient), and the user's
+// onboarding progress is replaced by a marker holding only their ID, so they
+// aren't introduced again.
+func (p *PrivacyClient) Forget(ctx context.Context, userID int64, emails ...string) (map[string]int, error) {
+	var counts map[string]int
+	err := p.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		// Transactions may be retried, so reset any state from earlier tries.
+		counts = make(map[string]int)
+
+		docs, err := p.find(tx, userID, emails)
+		if err != nil {
+			return err
+		}
+
+		for _, doc := range docs.all() {
+			counts[doc.Ref.Parent.ID]++
+			switch {
+			case doc == docs.onboarding:
+				// Replaced by the marker below, since a transaction can
+				// only write each document once.
+				continue
+			case slices.Contains(docs.messages, doc):
+				if err := forgetRecipient(tx, doc, userID); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := tx.Delete(doc.Ref); err != nil {
+				return err
+			}
+		}
+
+		ref := p.client.Collection("onboarding").Doc(strconv.FormatInt(userID, 10))
+		return tx.Set(ref, map[string]any{
+			"id":   userID,
+			"step": OnboardingForgotten,
+		})
+	})
+	return counts, err
+}
+
+// forgetRecipient deletes the message if it was only to the user. Messages
+// shared with other people, like a pair's match notification, are theirs too,
+// so the user is only taken off the recipients, along with any paragraph that
+// mentions them (like their pairing interests). A message that's still
+// pending is left pending, so the others still get it.
+func forgetRecipient(tx *firestore.Transaction, doc *firestore.DocumentSnapshot, userID int64) error {
+	var msg OutboxMessage
+	if err := decode(doc, &msg); err != nil {
+		return fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+	}
+
+	others := slices.DeleteFunc(msg.Recipients, func(id int64) bool { return id == userID })
+	if len(others) == 0 {
+		return tx.Delete(doc.Ref)
+	}
+
+	return tx.Update(doc.Ref, []firestore.Update{
+		{Path: "recipients", Value: others},
+		{Path: "content", Value: withoutMentions(msg.Content, userID)},
+	})
+}
+
+// withoutMentions removes the paragraphs of a message that mention the user.
+func withoutMentions(content string, userID int64) string {
+	mention := fmt.Sprintf("|%d**", userID)
+	paragraphs := strings.Split(content, "\n\n")
+	paragraphs = slices.DeleteFunc(paragraphs, func(p string) bool { return strings.Contains(p, mention) })
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// userDocs are the documents that refer to one person.
+type userDocs struct {
+	recurser     *firestore.DocumentSnapshot
+	reviews      []*firestore.DocumentSnapshot
+	messages     []*firestore.DocumentSnapshot
+	onboarding   *firestore.DocumentSnapshot
+	conversation *firestore.DocumentSnapshot
+}
+
+func (d userDocs) all() []*firestore.DocumentSnapshot {
+	var all []*firestore.DocumentSnapshot
+	if d.recurser != nil {
+		all = append(all, d.recurser)
+	}
+	all = append(all, d.reviews...)
+	all = append(all, d.messages...)
+	if d.onboarding != nil {
+		all = append(all, d.onboarding)
+	}
+	if d.conversation != nil {
+		all = append(all, d.conversation)
+	}
+	return all
+}
+
+func (d userDocs) decode() (*UserData, error) {
+	data := new(UserData)
+
+	if d.recurser != nil {
+		data.Recurser = new(Recurser)
+		if err := decode(d.recurser, data.Recurser); err != nil {
+			return nil, fmt.Errorf("parse document %q: %w", d.recurser.Ref.Path, err)
+		}
+		data.Recurser.IsSubscribed = !data.Recurser.Unsubscribed
+	}
+
+	for _, doc := range d.reviews {
+		var review Review
+		if err := decode(doc, &review); err != nil {
+			return nil, fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+		}
+		review.ID = doc.Ref.ID
+		data.Reviews = append(data.Reviews, review)
+	}
+
+	for _, doc := range d.messages {
+		var msg OutboxMessage
+		if err := decode(doc, &msg); err != nil {
+			return nil, fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+		}
+		msg.ID = doc.Ref.ID
+		data.Messages = append(data.Messages, msg)
+	}
+
+	if d.onboarding != nil {
+		data.Onboarding = new(OnboardingProgress)
+		if err := d.onboarding.DataTo(data.Onboarding); err != nil {
+			return nil, fmt.Errorf("parse document %q: %w", d.onboarding.Ref.Path, err)
+		}
+	}
+
+	if d.conversation != nil {
+		data.Conversation = new(Conversation)
+		if err := d.conversation.DataTo(data.Conversation); err != nil {
+			return nil, fmt.Errorf("parse document %q: %w", d.conversation.Ref.Path, err)
+		}
+	}
+
+	return data, nil
+}
+
+// find looks up every document that refers to the user within tx.
+func (p *PrivacyClient) find(tx *firestore.Transaction, userID int64, emails []string) (userDocs, error) {
+	var docs userDocs
+
+	ref := p.client.Collection("recursers").Doc(strconv.FormatInt(userID, 10))
+	doc, err := tx.Get(ref)
+	if err == nil {
+		docs.recurser = doc
+
+		var recurser Recurser
+		if err := decode(doc, &recurser); err == nil && recurser.Email != "" {
+			emails = append(emails, recurser.Email)
+		}
+	} else if status.Code(err) != codes.NotFound {
+		return docs, err
+	}
+
+	emails = slices.DeleteFunc(slices.Clone(emails), func(e string) bool { return e == "" })
+	slices.Sort(emails)
+	emails = slices.Compact(emails)
+
+	query := p.client.Collection("reviews").Where("authorId", "==", userID)
+	docs.reviews, err = allDocs(tx.Documents(query))
+	if err != nil {
+		return docs, err
+	}
+
+	if len(emails) > 0 {
+		query := p.client.Collection("reviews").Where("email", "in", emails)
+		byEmail, err := allDocs(tx.Documents(query))
+		if err != nil {
+			return docs, err
+		}
+
+		for _, doc := range byEmail {
+			found := slices.ContainsFunc(docs.reviews, func(d *firestore.DocumentSnapshot) bool {
+				return d.Ref.ID == doc.Ref.ID
+			})
+			if !found {
+				docs.reviews = append(docs.reviews, doc)
+			}
+		}
+	}
+
+	query = p.client.Collection("outbox").Where("recipients", "array-contains", userID)
+	docs.messages, err = allDocs(tx.Documents(query))
+	if err != nil {
+		return docs, err
+	}
+
+	ref = p.client.Collection("onboarding").Doc(strconv.FormatInt(userID, 10))
+	doc, err = tx.Get(ref)
+	if err == nil {
+		// The marker left by Forget isn't data about the user.
+		if step, _ := doc.DataAt("step"); step != OnboardingForgotten {
+			docs.onboarding = doc
+		}
+	} else if status.Code(err) != codes.NotFound {
+		return docs, err
+	}
+
+	ref = p.client.Collection("conversations").Doc(strconv.FormatInt(userID, 10))
+	doc, err = tx.Get(ref)
+	if err == nil {
+		docs.conversation = doc
+	} else if status.Code(err) != codes.NotFound {
+		return docs, err
+	}
+
+	return docs, nil
+}
+
+// allDocs collects the snapshots from iter without converting them.
+func allDocs(iter *firestore.DocumentIterator) ([]*firestore.DocumentSnapshot, error) {
+	var all []*firestore.DocumentSnapshot
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return all, nil
+		} else if err != nil {
+			return nil, err
+		}
+		all = append(all, doc)
+	}
+}
@@ -0,0 +1,112 @@
+package store_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func TestFirestorePrivacyClient(t *testing.T) {
+	ctx := context.Background()
+
+	client := pbtest.FirestoreClient(t, ctx)
+	privacy := store.Privacy(client)
+
+	recurser := store.Recurser{
+		ID:       pbtest.RandInt64(t),
+		Email:    "old@recurse.example.net",
+		Schedule: store.DefaultSchedule(),
+	}
+	if err := store.Recursers(client).Set(ctx, recurser.ID, &recurser); err != nil {
+		t.Fatal(err)
+	}
+
+	// One review from the email on their record, and one from their current
+	// Zulip email.
+	for _, email := range []string{"old@recurse.example.net", "new@recurse.example.net"} {
+		review := store.Review{Content: "test review", Email: email, Timestamp: pbtest.RandInt64(t)}
+		if _, err := store.Reviews(client).Insert(ctx, review); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A pending match message shared with a partner, and a message just for
+	// them.
+	partner := pbtest.RandInt64(t)
+	source := "test " + t.Name()
+	_, err := store.Outbox(client).Enqueue(ctx, source, []store.OutboxMessage{
+		{
+			Recipients: []int64{recurser.ID, partner},
+			Content: fmt.Sprintf("You're matched!\n\n@_**Your Name|%d** would like to pair on: Go\n\n@_**Partner|%d** would like to pair on: Rust",
+				recurser.ID, partner),
+		},
+		{Recipients: []int64{recurser.ID}, Content: "no match today"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := store.Onboarding(client).Start(ctx, recurser.ID, "Test Batch"); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := privacy.Collect(ctx, recurser.ID, "new@recurse.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, data.Recurser.ID, recurser.ID)
+	assert.Equal(t, len(data.Reviews), 2)
+	assert.Equal(t, len(data.Messages), 2)
+	assert.Equal(t, data.Onboarding.Batch, "Test Batch")
+
+	counts, err := privacy.Forget(ctx, recurser.ID, "new@recurse.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, counts, map[string]int{"recursers": 1, "reviews": 2, "outbox": 2, "onboarding": 1})
+
+	data, err = privacy.Collect(ctx, recurser.ID, "new@recurse.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !data.Empty() {
+		t.Errorf("expected nothing left after forgetting, got %+v", data)
+	}
+
+	// The partner still gets the shared message, without anything about
+	// the forgotten user.
+	messages, err := store.Outbox(client).ListFrom(ctx, source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("expected only the shared message to be left, got %+v", messages)
+	}
+	assert.Equal(t, messages[0].Recipients, []int64{partner})
+	assert.Equal(t, messages[0].Content, fmt.Sprintf("You're matched!\n\n@_**Partner|%d** would like to pair on: Rust", partner))
+	assert.Equal(t, messages[0].Status, store.OutboxPending)
+
+	// Only a marker is left, so that they aren't introduced again.
+	progress, err := store.Onboarding(client).Get(ctx, recurser.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, progress, &store.OnboardingProgress{ID: recurser.ID, Step: store.OnboardingForgotten})
+
+	started, err := store.Onboarding(client).Start(ctx, recurser.ID, "Test Batch")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, started, false)
+
+	// Forgetting them again finds nothing new.
+	counts, err = privacy.Forget(ctx, recurser.ID, "new@recurse.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, counts, map[string]int{})
+}
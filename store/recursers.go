@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -49,17 +50,72 @@ func EmptySchedule() map[string]bool {
 	}
 }
 
+// Partner preferences say who a Recurser wants to be matched with.
+const (
+	PartnersAnyone      = "anyone"
+	PartnersAlumniOnly  = "alumni-only"
+	PartnersCurrentOnly = "current-only"
+)
+
 type Recurser struct {
-	ID                 int64           `firestore:"id"`
-	Name               string          `firestore:"name"`
-	Email              string          `firestore:"email"`
-	IsSkippingTomorrow bool            `firestore:"isSkippingTomorrow"`
-	Schedule           map[string]bool `firestore:"schedule"`
-	CurrentlyAtRC      bool            `firestore:"currentlyAtRC"`
+	ID                 int64           `firestore:"id" json:"id"`
+	Name               string          `firestore:"name" json:"name"`
+	Email              string          `firestore:"email" json:"email"`
+	IsSkippingTomorrow bool            `firestore:"isSkippingTomorrow" json:"isSkippingTomorrow"`
+	SkipRequestedAt    time.Time       `firestore:"skipRequestedAt" json:"skipRequestedAt"`
+	Schedule           map[string]bool `firestore:"schedule" json:"schedule"`
+	CurrentlyAtRC      bool            `firestore:"currentlyAtRC" json:"currentlyAtRC"`
+	SchemaVersion      int             `firestore:"schemaVersion" json:"schemaVersion"`
+
+	// Alumni stay subscribed after they leave RC instead of being offboarded
+	// at the end of their batch.
+	Alumni bool `firestore:"alumni" json:"alumni"`
+
+	// Partners is one of the Partners* preferences. Empty means anyone.
+	Partners string `firestore:"partners" json:"partners"`
+
+	// Interests are what the user would like to pair on, in their own words.
+	// They're shared with the user's partners.
+	Interests string `firestore:"interests" json:"interests"`
+
+	// These are refreshed by the sync job. Batch is the name of the user's
+	// current batch, or their last one if they've left RC. Deactivated users
+	// have no active Zulip account for a human, so they can't be matched.
+	Batch       string    `firestore:"batch" json:"batch"`
+	Deactivated bool      `firestore:"deactivated" json:"deactivated"`
+	SyncedAt    time.Time `firestore:"syncedAt" json:"syncedAt"`
+
+	// Unsubscribed records are kept so that their preferences can be
+	// restored if they subscribe again. They are never matched.
+	Unsubscribed   bool      `firestore:"unsubscribed" json:"unsubscribed"`
+	UnsubscribedAt time.Time `firestore:"unsubscribedAt" json:"unsubscribedAt"`
+
+	// IsSubscribed means "has an entry in the database that isn't marked as
+	// unsubscribed". It is not written to or read from the Firestore document.
+	IsSubscribed bool `firestore:"-" json:"-"`
+}
 
-	// IsSubscribed really means "already had an entry in the database".
-	// It is not written to or read from the Firestore document.
-	IsSubscribed bool `firestore:"-"`
+// IsAlum returns whether the user has left RC. Someone who has opted in to
+// staying subscribed still counts as current until their batch ends.
+func (r *Recurser) IsAlum() bool {
+	return !r.CurrentlyAtRC
+}
+
+// CanPairWith returns whether both users' partner preferences allow matching
+// them with each other.
+func (r *Recurser) CanPairWith(other *Recurser) bool {
+	return r.accepts(other) && other.accepts(r)
+}
+
+func (r *Recurser) accepts(other *Recurser) bool {
+	switch r.Partners {
+	case PartnersAlumniOnly:
+		return other.IsAlum()
+	case PartnersCurrentOnly:
+		return !other.IsAlum()
+	default:
+		return true
+	}
 }
 
 // RecursersClient manages Pairing Bot subscribers ("Recursers").
@@ -88,16 +144,17 @@ func (r *RecursersClient) GetByUserID(ctx context.Context, userID int64, userEma
 			Name:     userName,
 			Email:    userEmail,
 			Schedule: DefaultSchedule(),
+			Partners: PartnersAnyone,
 		}, nil
 	}
 
 	var recurser Recurser
-	if err := doc.DataTo(&recurser); err != nil {
+	if err := decode(doc, &recurser); err != nil {
 		return nil, fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
 	}
 
 	// This field isn't stored in the DB, so populate it now.
-	recurser.IsSubscribed = true
+	recurser.IsSubscribed = !recurser.Unsubscribed
 
 	// Prefer the Zulip values for these fields over our cached ones.
 	recurser.Name = userName
@@ -106,9 +163,48 @@ func (r *RecursersClient) GetByUserID(ctx context.Context, userID int64, userEma
 	return &recurser, nil
 }
 
+// Get returns the stored record for the user, including one kept after they
+// unsubscribed. Unlike GetByUserID, this returns a NotFound error if there is
+// no record at all.
+func (r *RecursersClient) Get(ctx context.Context, userID int64) (*Recurser, error) {
+	docID := strconv.FormatInt(userID, 10)
+	doc, err := r.client.Collection("recursers").Doc(docID).Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var recurser Recurser
+	if err := decode(doc, &recurser); err != nil {
+		return nil, fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+	}
+
+	recurser.IsSubscribed = !recurser.Unsubscribed
+	return &recurser, nil
+}
+
+// GetAllUsers returns everyone who is currently subscribed.
 func (r *RecursersClient) GetAllUsers(ctx context.Context) ([]Recurser, error) {
 	iter := r.client.Collection("recursers").Documents(ctx)
-	return fetchAll[Recurser](iter)
+	return subscribedOnly(fetchAll[Recurser](iter))
+}
+
+// subscribedOnly drops unsubscribed records from the results of a query.
+//
+// This filters after the fact instead of in the query because Firestore
+// filters never match documents that are missing the field, and records from
+// before unsubscribing was recorded don't have it.
+func subscribedOnly(recursers []Recurser, err error) ([]Recurser, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	recursers = slices.DeleteFunc(recursers, func(r Recurser) bool {
+		return r.Unsubscribed
+	})
+	for i := range recursers {
+		recursers[i].IsSubscribed = true
+	}
+	return recursers, nil
 }
 
 func (r *RecursersClient) Set(ctx context.Context, _ int64, recurser *Recurser) error {
@@ -117,11 +213,40 @@ func (r *RecursersClient) Set(ctx context.Context, _ int64, recurser *Recurser)
 	// Merging isn't supported when using struct data, but we never do partial
 	// writes in the first place. So this will completely overwrite an existing
 	// document.
+	recurser.SchemaVersion = CurrentSchemaVersion("recursers")
 	_, err := r.client.Collection("recursers").Doc(docID).Set(ctx, recurser)
 	return err
 
 }
 
+// Unsubscribe stops matching the user but keeps their record, so that their
+// preferences can be restored if they subscribe again. This returns a NotFound
+// error if there is no record.
+func (r *RecursersClient) Unsubscribe(ctx context.Context, userID int64) error {
+	return r.update(ctx, userID,
+		firestore.Update{Path: "unsubscribed", Value: true},
+		firestore.Update{Path: "unsubscribedAt", Value: time.Now()},
+		firestore.Update{Path: "isSkippingTomorrow", Value: false},
+	)
+}
+
+// Resubscribe starts matching a user who unsubscribed before. Their old
+// preferences are kept, other than the schedule, which is replaced unless it's
+// nil. This returns a NotFound error if there is no record.
+func (r *RecursersClient) Resubscribe(ctx context.Context, userID int64, atRC bool, schedule map[string]bool) error {
+	updates := []firestore.Update{
+		{Path: "unsubscribed", Value: false},
+		{Path: "isSkippingTomorrow", Value: false},
+		{Path: "currentlyAtRC", Value: atRC},
+	}
+	if schedule != nil {
+		updates = append(updates, firestore.Update{Path: "schedule", Value: schedule})
+	}
+	return r.update(ctx, userID, updates...)
+}
+
+// Delete permanently removes the user's record. Use Unsubscribe to stop
+// matching someone without forgetting their preferences.
 func (r *RecursersClient) Delete(ctx context.Context, userID int64) error {
 	docID := strconv.FormatInt(userID, 10)
 	_, err := r.client.Collection("recursers").Doc(docID).Delete(ctx)
@@ -140,7 +265,11 @@ func (r *RecursersClient) ListPairingTomorrow(ctx context.Context) ([]Recurser,
 		Where("isSkippingTomorrow", "==", false).
 		Where("schedule."+today, "==", true).
 		Documents(ctx)
-	return fetchAll[Recurser](iter)
+
+	recursers, err := subscribedOnly(fetchAll[Recurser](iter))
+	return slices.DeleteFunc(recursers, func(r Recurser) bool {
+		return r.Deactivated
+	}), err
 }
 
 func (r *RecursersClient) ListSkippingTomorrow(ctx context.Context) ([]Recurser, error) {
@@ -148,10 +277,120 @@ func (r *RecursersClient) ListSkippingTomorrow(ctx context.Context) ([]Recurser,
 		Collection("recursers").
 		Where("isSkippingTomorrow", "==", true).
 		Documents(ctx)
-	return fetchAll[Recurser](iter)
+	return subscribedOnly(fetchAll[Recurser](iter))
+}
+
+// SetSchedule replaces the user's schedule without touching any other fields.
+// This returns a NotFound error if the user isn't subscribed.
+func (r *RecursersClient) SetSchedule(ctx context.Context, userID int64, schedule map[string]bool) error {
+	return r.update(ctx, userID, firestore.Update{Path: "schedule", Value: schedule})
+}
+
+// SetSkippingTomorrow sets whether to skip the user in the next match. This
+// returns a NotFound error if the user isn't subscribed.
+func (r *RecursersClient) SetSkippingTomorrow(ctx context.Context, userID int64, skip bool) error {
+	return r.update(ctx, userID,
+		firestore.Update{Path: "isSkippingTomorrow", Value: skip},
+		firestore.Update{Path: "skipRequestedAt", Value: time.Now()},
+	)
+}
+
+// SetCurrentlyAtRC records whether the user is currently at RC. This returns
+// a NotFound error if the user isn't subscribed.
+func (r *RecursersClient) SetCurrentlyAtRC(ctx context.Context, userID int64, atRC bool) error {
+	return r.update(ctx, userID, firestore.Update{Path: "currentlyAtRC", Value: atRC})
+}
+
+// SetAlumni sets whether the user stays subscribed after leaving RC. This
+// returns a NotFound error if the user isn't subscribed.
+func (r *RecursersClient) SetAlumni(ctx context.Context, userID int64, alumni bool) error {
+	return r.update(ctx, userID, firestore.Update{Path: "alumni", Value: alumni})
+}
+
+// SetInterests replaces what the user would like to pair on. This returns a
+// NotFound error if the user isn't subscribed.
+func (r *RecursersClient) SetInterests(ctx context.Context, userID int64, interests string) error {
+	return r.update(ctx, userID, firestore.Update{Path: "interests", Value: interests})
+}
+
+// SetPartners sets who the user wants to be matched with. This returns a
+// NotFound error if the user isn't subscribed.
+func (r *RecursersClient) SetPartners(ctx context.Context, userID int64, partners string) error {
+	return r.update(ctx, userID, firestore.Update{Path: "partners", Value: partners})
 }
 
-func (r *RecursersClient) UnsetSkippingTomorrow(ctx context.Context, recurser *Recurser) error {
-	recurser.IsSkippingTomorrow = false
-	return r.Set(ctx, recurser.ID, recurser)
+// SyncedFields are the parts of a record that are copied from Zulip and the
+// Recurse API.
+//
+// CurrentlyAtRC isn't one of them: the end-of-batch job compares it to the
+// Recurse API to find out who just left.
+type SyncedFields struct {
+	Name        string
+	Email       string
+	Batch       string
+	Deactivated bool
+}
+
+// Synced returns the record's current synced fields.
+func (r *Recurser) Synced() SyncedFields {
+	return SyncedFields{
+		Name:        r.Name,
+		Email:       r.Email,
+		Batch:       r.Batch,
+		Deactivated: r.Deactivated,
+	}
+}
+
+// Sync overwrites the synced fields and records when it happened. This returns
+// a NotFound error if the user has no record.
+func (r *RecursersClient) Sync(ctx context.Context, userID int64, fields SyncedFields) error {
+	return r.update(ctx, userID,
+		firestore.Update{Path: "name", Value: fields.Name},
+		firestore.Update{Path: "email", Value: fields.Email},
+		firestore.Update{Path: "batch", Value: fields.Batch},
+		firestore.Update{Path: "deactivated", Value: fields.Deactivated},
+		firestore.Update{Path: "syncedAt", Value: time.Now()},
+	)
+}
+
+// SetDeactivated flags the user's record when their Zulip account can't be
+// messaged, so they stop being matched until the sync job sees the account is
+// active again. This returns a NotFound error if the user has no record.
+func (r *RecursersClient) SetDeactivated(ctx context.Context, userID int64, deactivated bool) error {
+	return r.update(ctx, userID, firestore.Update{Path: "deactivated", Value: deactivated})
+}
+
+// update changes only the given fields of an existing record, so concurrent
+// updates to other fields aren't lost.
+func (r *RecursersClient) update(ctx context.Context, userID int64, updates ...firestore.Update) error {
+	docID := strconv.FormatInt(userID, 10)
+	_, err := r.client.Collection("recursers").Doc(docID).Update(ctx, updates, firestore.Exists)
+	return err
+}
+
+// UnsetSkippingTomorrow clears the user's skip request once the match it was
+// meant for has happened. Skips requested after asOf are for the *next* match,
+// so they're left alone. Unsubscribed users are ignored.
+func (r *RecursersClient) UnsetSkippingTomorrow(ctx context.Context, userID int64, asOf time.Time) error {
+	docID := strconv.FormatInt(userID, 10)
+	ref := r.client.Collection("recursers").Doc(docID)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if status.Code(err) == codes.NotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		var recurser Recurser
+		if err := decode(doc, &recurser); err != nil {
+			return fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+		}
+
+		if !recurser.IsSkippingTomorrow || recurser.SkipRequestedAt.After(asOf) {
+			return nil
+		}
+		return tx.Update(ref, []firestore.Update{{Path: "isSkippingTomorrow", Value: false}})
+	})
 }
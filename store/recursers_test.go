@@ -3,7 +3,9 @@ package store_test
 import (
 	"context"
 	"strconv"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/recursecenter/pairing-bot/internal/assert"
 	"github.com/recursecenter/pairing-bot/internal/pbtest"
@@ -74,5 +76,221 @@ func TestFirestoreRecursersClient(t *testing.T) {
 
 		assert.Equal(t, actual, recurser)
 	})
-}
 
+	t.Run("field updates don't clobber each other", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		recursers := store.Recursers(client)
+
+		recurser := store.Recurser{
+			ID:       pbtest.RandInt64(t),
+			Schedule: store.DefaultSchedule(),
+		}
+		if err := recursers.Set(ctx, recurser.ID, &recurser); err != nil {
+			t.Fatal(err)
+		}
+
+		// Each of these used to read the whole record and write it back, so
+		// whichever finished last would undo the others.
+		schedule := store.NewSchedule([]string{"tuesday"})
+		updates := []func() error{
+			func() error { return recursers.SetSchedule(ctx, recurser.ID, schedule) },
+			func() error { return recursers.SetSkippingTomorrow(ctx, recurser.ID, true) },
+			func() error { return recursers.SetCurrentlyAtRC(ctx, recurser.ID, true) },
+		}
+
+		var wg sync.WaitGroup
+		for _, update := range updates {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if err := update(); err != nil {
+					t.Error(err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		actual, err := recursers.Get(ctx, recurser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		assert.Equal(t, actual.Schedule, schedule)
+		assert.Equal(t, actual.IsSkippingTomorrow, true)
+		assert.Equal(t, actual.CurrentlyAtRC, true)
+	})
+
+	t.Run("field updates require a subscription", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		recursers := store.Recursers(client)
+
+		id := pbtest.RandInt64(t)
+		if err := recursers.SetSkippingTomorrow(ctx, id, true); err == nil {
+			t.Error("expected an error updating a missing recurser")
+		}
+
+		// Updating must not have created a partial record.
+		if _, err := recursers.Get(ctx, id); err == nil {
+			t.Error("expected recurser to still be missing")
+		}
+	})
+
+	t.Run("unskip keeps skips requested during the match", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		recursers := store.Recursers(client)
+
+		early := store.Recurser{ID: pbtest.RandInt64(t), Schedule: store.DefaultSchedule()}
+		late := store.Recurser{ID: pbtest.RandInt64(t), Schedule: store.DefaultSchedule()}
+		for _, r := range []*store.Recurser{&early, &late} {
+			if err := recursers.Set(ctx, r.ID, r); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if err := recursers.SetSkippingTomorrow(ctx, early.ID, true); err != nil {
+			t.Fatal(err)
+		}
+
+		// The match job starts here.
+		matchStarted := time.Now()
+
+		// This skip is for the next match, not the one that's running.
+		if err := recursers.SetSkippingTomorrow(ctx, late.ID, true); err != nil {
+			t.Fatal(err)
+		}
+
+		for _, id := range []int64{early.ID, late.ID} {
+			if err := recursers.UnsetSkippingTomorrow(ctx, id, matchStarted); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		actual, err := recursers.Get(ctx, early.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, actual.IsSkippingTomorrow, false)
+
+		actual, err = recursers.Get(ctx, late.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, actual.IsSkippingTomorrow, true)
+	})
+
+	t.Run("deactivated accounts aren't matched", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		recursers := store.Recursers(client)
+
+		recurser := store.Recurser{
+			ID:       pbtest.RandInt64(t),
+			Name:     "Old Name",
+			Schedule: store.NewSchedule([]string{"monday", "tuesday", "wednesday", "thursday", "friday", "saturday", "sunday"}),
+		}
+		if err := recursers.Set(ctx, recurser.ID, &recurser); err != nil {
+			t.Fatal(err)
+		}
+
+		fields := store.SyncedFields{
+			Name:        "New Name",
+			Email:       "new@recurse.example.net",
+			Batch:       "Fall 2, 2023",
+			Deactivated: true,
+		}
+		if err := recursers.Sync(ctx, recurser.ID, fields); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := recursers.Get(ctx, recurser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, actual.Synced(), fields)
+
+		pairing, err := recursers.ListPairingTomorrow(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range pairing {
+			if r.ID == recurser.ID {
+				t.Errorf("deactivated recurser %d would be matched", r.ID)
+			}
+		}
+	})
+
+	t.Run("unsubscribe keeps preferences", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		recursers := store.Recursers(client)
+
+		recurser := store.Recurser{
+			ID:       pbtest.RandInt64(t),
+			Schedule: store.NewSchedule([]string{"wednesday"}),
+		}
+		if err := recursers.Set(ctx, recurser.ID, &recurser); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := recursers.Unsubscribe(ctx, recurser.ID); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err := recursers.Get(ctx, recurser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, actual.IsSubscribed, false)
+		assert.Equal(t, actual.Unsubscribed, true)
+		assert.Equal(t, actual.Schedule, recurser.Schedule)
+
+		all, err := recursers.GetAllUsers(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, r := range all {
+			if r.ID == recurser.ID {
+				t.Errorf("unsubscribed recurser %d was listed as a subscriber", r.ID)
+			}
+		}
+
+		// Resubscribing only changes what it's asked to.
+		if err := recursers.Set(ctx, recurser.ID, &recurser); err != nil {
+			t.Fatal(err)
+		}
+		if err := recursers.SetInterests(ctx, recurser.ID, "compilers"); err != nil {
+			t.Fatal(err)
+		}
+		if err := recursers.Unsubscribe(ctx, recurser.ID); err != nil {
+			t.Fatal(err)
+		}
+		if err := recursers.Resubscribe(ctx, recurser.ID, true, nil); err != nil {
+			t.Fatal(err)
+		}
+
+		actual, err = recursers.Get(ctx, recurser.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, actual.IsSubscribed, true)
+		assert.Equal(t, actual.CurrentlyAtRC, true)
+		assert.Equal(t, actual.Schedule, recurser.Schedule)
+		assert.Equal(t, actual.Interests, "compilers")
+
+		// Deleting is permanent.
+		if err := recursers.Delete(ctx, recurser.ID); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := recursers.Get(ctx, recurser.ID); err == nil {
+			t.Error("expected deleted recurser to be missing")
+		}
+	})
+}
@@ -1,18 +1,57 @@
 package store
 
 import (
+	"cmp"
 	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"cloud.google.com/go/firestore"
 )
 
+// Review moderation states. New reviews start out pending and are only shown
+// publicly once a maintainer approves them.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewRejected = "rejected"
+)
+
 type Review struct {
-	Content   string `firestore:"content"`
-	Email     string `firestore:"email"`
-	Timestamp int64  `firestore:"timestamp"`
+	// ID is the Firestore document ID. It is not stored in the document.
+	ID string `firestore:"-" json:"id"`
+
+	Content   string `firestore:"content" json:"content"`
+	Email     string `firestore:"email" json:"email"`
+	AuthorID  int64  `firestore:"authorId" json:"authorId"`
+	Timestamp int64  `firestore:"timestamp" json:"timestamp"`
+	EditedAt  int64  `firestore:"editedAt" json:"editedAt"`
+
+	Status      string `firestore:"status" json:"status"`
+	ModeratedBy int64  `firestore:"moderatedBy" json:"moderatedBy"`
+
+	SchemaVersion int `firestore:"schemaVersion" json:"schemaVersion"`
 }
 
+// IsAuthor returns whether the user wrote the review. Reviews written before
+// author IDs were recorded only have the author's email.
+func (rv Review) IsAuthor(userID int64, email string) bool {
+	if rv.AuthorID != 0 {
+		return rv.AuthorID == userID
+	}
+	return email != "" && rv.Email == email
+}
+
+// ErrNotAuthor is returned when someone tries to change another person's
+// review.
+var ErrNotAuthor = errors.New("review was written by someone else")
+
 // ReviewsClient manages user-submitted Pairing Bot reviews.
 type ReviewsClient struct {
 	client *firestore.Client
@@ -22,31 +61,297 @@ func Reviews(client *firestore.Client) *ReviewsClient {
 	return &ReviewsClient{client}
 }
 
+// GetAll returns every review, whatever its moderation status.
 func (r *ReviewsClient) GetAll(ctx context.Context) ([]Review, error) {
 	iter := r.client.Collection("reviews").Documents(ctx)
-	return fetchAll[Review](iter)
+	return fetchReviews(iter)
 }
 
+// GetLastN returns the n most recent approved reviews, newest first.
 func (r *ReviewsClient) GetLastN(ctx context.Context, n int) ([]Review, error) {
 	iter := r.client.
 		Collection("reviews").
+		Where("status", "==", ReviewApproved).
 		OrderBy("timestamp", firestore.Desc).
 		Limit(n).
 		Documents(ctx)
-	return fetchAll[Review](iter)
+	return fetchReviews(iter)
 }
 
+// ErrNoReviews is returned by GetRandom when there are no approved reviews.
+var ErrNoReviews = errors.New("no approved reviews")
+
+// GetRandom returns one of the approved reviews.
+//
+// Rather than loading every review, this picks a random document ID and
+// returns the first approved review at or after it. That isn't a uniform
+// choice: each review is picked with probability proportional to the gap
+// between its ID and the previous review's, and with randomly generated IDs
+// some gaps are several times longer than others. It's fair enough for
+// showing a review in the weekly checkin, but not for anything that needs
+// every review to be equally likely.
 func (r *ReviewsClient) GetRandom(ctx context.Context) (Review, error) {
-	allReviews, err := r.GetAll(ctx)
+	query := r.client.
+		Collection("reviews").
+		Where("status", "==", ReviewApproved).
+		OrderBy(firestore.DocumentID, firestore.Asc).
+		Limit(1)
 
+	reviews, err := fetchReviews(query.StartAt(randomDocID()).Documents(ctx))
 	if err != nil {
 		return Review{}, err
 	}
 
-	return allReviews[rand.Intn(len(allReviews))], nil
+	// We might have picked an ID after the last review, so wrap around to
+	// the first one.
+	if len(reviews) == 0 {
+		reviews, err = fetchReviews(query.Documents(ctx))
+		if err != nil {
+			return Review{}, err
+		}
+	}
+
+	if len(reviews) == 0 {
+		return Review{}, ErrNoReviews
+	}
+	return reviews[0], nil
+}
+
+// randomDocID returns a random ID in the same format that Firestore uses for
+// generated document IDs.
+func randomDocID() string {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+	b := make([]byte, 20)
+	for i := range b {
+		b[i] = alphabet[rand.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+// A ReviewQuery selects one page of approved reviews.
+type ReviewQuery struct {
+	// Since and Until limit the reviews to those submitted in [Since,
+	// Until). Zero values leave that end unbounded.
+	Since time.Time
+	Until time.Time
+
+	// After is the cursor returned with the previous page, or empty for
+	// the first page. PageSize is the number of reviews on each page.
+	After    ReviewCursor
+	PageSize int
+}
+
+// A ReviewCursor marks the last review on a page, so that the next page can
+// start right after it without reading the reviews before it. Cursors only
+// use lowercase letters, digits, and dashes, so they survive being sent to
+// users and back in a command.
+type ReviewCursor string
+
+// newReviewCursor returns the cursor for the page that ends with the review.
+func newReviewCursor(review Review) ReviewCursor {
+	return ReviewCursor(strconv.FormatInt(review.Timestamp, 36) + "-" + hex.EncodeToString([]byte(review.ID)))
+}
+
+// ErrInvalidCursor is returned for cursors that ListApproved didn't make.
+var ErrInvalidCursor = errors.New("invalid review cursor")
+
+// ParseReviewCursor checks that s is a cursor returned by ListApproved.
+func ParseReviewCursor(s string) (ReviewCursor, error) {
+	cursor := ReviewCursor(s)
+	if _, _, err := cursor.decode(); err != nil {
+		return "", err
+	}
+	return cursor, nil
+}
+
+// decode returns the timestamp and document ID of the review the cursor
+// marks.
+func (c ReviewCursor) decode() (int64, string, error) {
+	timestamp, id, ok := strings.Cut(string(c), "-")
+	if !ok {
+		return 0, "", ErrInvalidCursor
+	}
+
+	ts, err := strconv.ParseInt(timestamp, 36, 64)
+	if err != nil {
+		return 0, "", ErrInvalidCursor
+	}
+	decoded, err := hex.DecodeString(id)
+	if err != nil || len(decoded) == 0 {
+		return 0, "", ErrInvalidCursor
+	}
+	return ts, string(decoded), nil
 }
 
-func (r *ReviewsClient) Insert(ctx context.Context, review Review) error {
-	_, _, err := r.client.Collection("reviews").Add(ctx, review)
+// ListApproved returns a page of approved reviews, newest first, and the
+// cursor for the next page. The cursor is empty if there are no more pages.
+func (r *ReviewsClient) ListApproved(ctx context.Context, q ReviewQuery) ([]Review, ReviewCursor, error) {
+	query := r.client.
+		Collection("reviews").
+		Where("status", "==", ReviewApproved)
+	if !q.Since.IsZero() {
+		query = query.Where("timestamp", ">=", q.Since.Unix())
+	}
+	if !q.Until.IsZero() {
+		query = query.Where("timestamp", "<", q.Until.Unix())
+	}
+	// Break ties between reviews from the same second so that pages never
+	// overlap or skip any.
+	query = query.
+		OrderBy("timestamp", firestore.Desc).
+		OrderBy(firestore.DocumentID, firestore.Desc)
+
+	if q.After != "" {
+		timestamp, id, err := q.After.decode()
+		if err != nil {
+			return nil, "", err
+		}
+		query = query.StartAfter(timestamp, id)
+	}
+
+	// Ask for one extra review to find out whether there's another page.
+	reviews, err := fetchReviews(query.Limit(q.PageSize + 1).Documents(ctx))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if len(reviews) <= q.PageSize {
+		return reviews, "", nil
+	}
+
+	reviews = reviews[:q.PageSize]
+	return reviews, newReviewCursor(reviews[len(reviews)-1]), nil
+}
+
+// ListByStatus returns the reviews in the given moderation state, oldest
+// first.
+func (r *ReviewsClient) ListByStatus(ctx context.Context, status string) ([]Review, error) {
+	iter := r.client.
+		Collection("reviews").
+		Where("status", "==", status).
+		OrderBy("timestamp", firestore.Asc).
+		Documents(ctx)
+	return fetchReviews(iter)
+}
+
+// Insert stores a new review and returns its ID. Reviews without a status are
+// stored as pending.
+func (r *ReviewsClient) Insert(ctx context.Context, review Review) (string, error) {
+	review.SchemaVersion = CurrentSchemaVersion("reviews")
+	if review.Status == "" {
+		review.Status = ReviewPending
+	}
+
+	ref, _, err := r.client.Collection("reviews").Add(ctx, review)
+	if err != nil {
+		return "", err
+	}
+	return ref.ID, nil
+}
+
+// Moderate sets the review's status and records which maintainer decided it.
+// This returns a NotFound error if there is no review with the ID.
+func (r *ReviewsClient) Moderate(ctx context.Context, id string, status string, moderatorID int64) error {
+	_, err := r.client.Collection("reviews").Doc(id).Update(ctx, []firestore.Update{
+		{Path: "status", Value: status},
+		{Path: "moderatedBy", Value: moderatorID},
+	}, firestore.Exists)
 	return err
 }
+
+// ListByAuthor returns the user's reviews, whatever their moderation status,
+// oldest first.
+func (r *ReviewsClient) ListByAuthor(ctx context.Context, userID int64, email string) ([]Review, error) {
+	byID, err := fetchReviews(r.client.
+		Collection("reviews").
+		Where("authorId", "==", userID).
+		Documents(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	// Older reviews can only be found by email.
+	byEmail, err := fetchReviews(r.client.
+		Collection("reviews").
+		Where("email", "==", email).
+		Documents(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	all := byID
+	for _, review := range byEmail {
+		if review.AuthorID == 0 {
+			all = append(all, review)
+		}
+	}
+
+	slices.SortFunc(all, func(a, b Review) int {
+		return cmp.Compare(a.Timestamp, b.Timestamp)
+	})
+	return all, nil
+}
+
+// Edit replaces the content of one of the user's reviews. Edited reviews go
+// back to pending, since the new content hasn't been moderated yet. This
+// returns ErrNotAuthor if the user didn't write the review, or a NotFound
+// error if there is no review with the ID.
+func (r *ReviewsClient) Edit(ctx context.Context, id string, userID int64, email string, content string) error {
+	return r.changeOwn(ctx, id, userID, email, func(tx *firestore.Transaction, ref *firestore.DocumentRef) error {
+		return tx.Update(ref, []firestore.Update{
+			{Path: "content", Value: content},
+			{Path: "authorId", Value: userID},
+			{Path: "editedAt", Value: time.Now().Unix()},
+			{Path: "status", Value: ReviewPending},
+			{Path: "moderatedBy", Value: 0},
+		})
+	})
+}
+
+// Delete removes one of the user's reviews. This returns ErrNotAuthor if the
+// user didn't write the review, or a NotFound error if there is no review with
+// the ID.
+func (r *ReviewsClient) Delete(ctx context.Context, id string, userID int64, email string) error {
+	return r.changeOwn(ctx, id, userID, email, func(tx *firestore.Transaction, ref *firestore.DocumentRef) error {
+		return tx.Delete(ref)
+	})
+}
+
+// changeOwn runs change on the review only if the user wrote it. The check
+// and the change happen in one transaction.
+func (r *ReviewsClient) changeOwn(ctx context.Context, id string, userID int64, email string, change func(*firestore.Transaction, *firestore.DocumentRef) error) error {
+	ref := r.client.Collection("reviews").Doc(id)
+
+	return r.client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+		doc, err := tx.Get(ref)
+		if err != nil {
+			return err
+		}
+
+		var review Review
+		if err := decode(doc, &review); err != nil {
+			return fmt.Errorf("parse document %q: %w", doc.Ref.Path, err)
+		}
+
+		if !review.IsAuthor(userID, email) {
+			return ErrNotAuthor
+		}
+		return change(tx, ref)
+	})
+}
+
+// fetchReviews is like fetchAll, but it also fills in each review's ID.
+func fetchReviews(iter *firestore.DocumentIterator) ([]Review, error) {
+	docs, err := fetchDocs[Review](iter)
+	if err != nil {
+		return nil, err
+	}
+
+	var all []Review
+	for _, doc := range docs {
+		doc.Data.ID = doc.ID
+		all = append(all, doc.Data)
+	}
+	return all, nil
+}
@@ -2,7 +2,11 @@ package store_test
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/recursecenter/pairing-bot/internal/assert"
 	"github.com/recursecenter/pairing-bot/internal/pbtest"
@@ -22,11 +26,31 @@ func TestFirestoreReviewsClient(t *testing.T) {
 			Timestamp: pbtest.RandInt64(t),
 		}
 
-		err := reviews.Insert(ctx, review)
+		id, err := reviews.Insert(ctx, review)
 		if err != nil {
 			t.Fatal(err)
 		}
 
+		// New reviews aren't shown until they're approved.
+		pending, err := reviews.ListByStatus(ctx, store.ReviewPending)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !slices.ContainsFunc(pending, func(r store.Review) bool { return r.ID == id }) {
+			t.Errorf("expected review %s to be pending", id)
+		}
+
+		moderator := pbtest.RandInt64(t)
+		if err := reviews.Moderate(ctx, id, store.ReviewApproved, moderator); err != nil {
+			t.Fatal(err)
+		}
+
+		// Insert stamps the review with its ID and the current schema version.
+		review.ID = id
+		review.Status = store.ReviewApproved
+		review.ModeratedBy = moderator
+		review.SchemaVersion = store.CurrentSchemaVersion("reviews")
+
 		// Reviews are returned as a slice, even for just one review
 		expected := []store.Review{review}
 
@@ -37,4 +61,120 @@ func TestFirestoreReviewsClient(t *testing.T) {
 
 		assert.Equal(t, actual, expected)
 	})
+
+	t.Run("only authors can change reviews", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		reviews := store.Reviews(client)
+
+		author := pbtest.RandInt64(t)
+		email := "author@recurse.example.net"
+
+		id, err := reviews.Insert(ctx, store.Review{
+			Content:   "tset review",
+			Email:     email,
+			AuthorID:  author,
+			Timestamp: pbtest.RandInt64(t),
+			Status:    store.ReviewApproved,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Someone else can't touch it, even with the same email.
+		err = reviews.Edit(ctx, id, pbtest.RandInt64(t), email, "hijacked")
+		assert.ErrorIs(t, err, store.ErrNotAuthor)
+		err = reviews.Delete(ctx, id, pbtest.RandInt64(t), email)
+		assert.ErrorIs(t, err, store.ErrNotAuthor)
+
+		// The author can fix a typo, which needs moderation again.
+		if err := reviews.Edit(ctx, id, author, email, "test review"); err != nil {
+			t.Fatal(err)
+		}
+
+		mine, err := reviews.ListByAuthor(ctx, author, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if assert.Equal(t, len(mine), 1) {
+			assert.Equal(t, mine[0].Content, "test review")
+			assert.Equal(t, mine[0].Status, store.ReviewPending)
+		}
+
+		if err := reviews.Delete(ctx, id, author, email); err != nil {
+			t.Fatal(err)
+		}
+
+		mine, err = reviews.ListByAuthor(ctx, author, email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, len(mine), 0)
+	})
+	t.Run("pages of approved reviews", func(t *testing.T) {
+		ctx := context.Background()
+
+		client := pbtest.FirestoreClient(t, ctx)
+		reviews := store.Reviews(client)
+
+		// Put these reviews in their own window of time so that the date
+		// filter excludes reviews from other tests.
+		start := time.Unix(pbtest.RandInt64(t)%(1<<40), 0)
+		var want []string
+		for i := range 5 {
+			id, err := reviews.Insert(ctx, store.Review{
+				Content:   fmt.Sprintf("review %d", i),
+				Timestamp: start.Unix() + int64(i),
+				Status:    store.ReviewApproved,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Newest first.
+			want = append([]string{id}, want...)
+		}
+
+		var got []string
+		var after store.ReviewCursor
+		for {
+			batch, next, err := reviews.ListApproved(ctx, store.ReviewQuery{
+				Since:    start,
+				Until:    start.Add(time.Minute),
+				After:    after,
+				PageSize: 2,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, r := range batch {
+				got = append(got, r.ID)
+			}
+			if next == "" {
+				break
+			}
+
+			// Cursors survive being sent to users and back.
+			after, err = store.ParseReviewCursor(strings.ToLower(string(next)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, after, next)
+		}
+
+		assert.Equal(t, got, want)
+
+		random, err := reviews.GetRandom(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, random.Status, store.ReviewApproved)
+	})
+}
+
+func TestParseReviewCursor_invalid(t *testing.T) {
+	for _, s := range []string{"", "abc", "zz-", "-6964", "1-not-hex", "!-6964"} {
+		_, err := store.ParseReviewCursor(s)
+		assert.ErrorIs(t, err, store.ErrInvalidCursor)
+	}
 }
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/api/iterator"
+)
+
+// A Migration upgrades a document's raw fields from one schema version to the
+// next, in place.
+type Migration func(data map[string]any) error
+
+// migrations lists the upgrades for each versioned collection, in order. The
+// migration at index i upgrades a document from version i to version i+1, so
+// the current version of a collection is the number of migrations it has.
+//
+// Documents written before schema versioning existed have no schemaVersion
+// field and are treated as version 0.
+//
+// To change a document type, add a field or rename one in the struct, then
+// append a migration here that rewrites older documents to match.
+var migrations = map[string][]Migration{
+	"recursers": {
+		// 0 -> 1: Introduce the schema version. No other changes.
+		noChanges,
+		// 1 -> 2: Add skipRequestedAt. We don't know when older skips were
+		// requested, so leave it unset; the next match will clear them.
+		noChanges,
+		// 2 -> 3: Add unsubscribed and unsubscribedAt. Before this, records
+		// were deleted on unsubscribe, so every existing one is subscribed.
+		noChanges,
+		// 3 -> 4: Add alumni. Nobody has opted in yet.
+		noChanges,
+		// 4 -> 5: Add partner preferences. Everyone used to be matched with
+		// anyone.
+		func(data map[string]any) error {
+			if _, ok := data["partners"]; !ok {
+				data["partners"] = PartnersAnyone
+			}
+			return nil
+		},
+		// 5 -> 6: Add batch, deactivated, and syncedAt. These stay unset
+		// until the next sync.
+		noChanges,
+		// 6 -> 7: Add interests. Nobody has shared any yet.
+		noChanges,
+	},
+	"reviews": {
+		// 0 -> 1: Introduce the schema version. No other changes.
+		noChanges,
+		// 1 -> 2: Add moderation. Reviews from before moderation were
+		// already public, so they count as approved.
+		func(data map[string]any) error {
+			if _, ok := data["status"]; !ok {
+				data["status"] = ReviewApproved
+			}
+			return nil
+		},
+		// 2 -> 3: Add authorId and editedAt. Older reviews were never edited,
+		// and their authors can only be identified by email (see IsAuthor).
+		noChanges,
+	},
+	"pairings": {
+		// 0 -> 1: Introduce the schema version. No other changes.
+		noChanges,
+		// 1 -> 2: Add alumni. Alumni weren't counted before, so leave it at
+		// zero.
+		noChanges,
+	},
+}
+
+func noChanges(map[string]any) error { return nil }
+
+// CurrentSchemaVersion returns the current schema version for documents in the
+// collection.
+func CurrentSchemaVersion(collection string) int {
+	return len(migrations[collection])
+}
+
+// documentVersion returns the schema version the document was written with.
+func documentVersion(doc *firestore.DocumentSnapshot) int {
+	v, err := doc.DataAt("schemaVersion")
+	if err != nil {
+		return 0
+	}
+
+	switch v := v.(type) {
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	default:
+		return 0
+	}
+}
+
+// decode converts the document into item, first upgrading its data if it was
+// written with an older schema version.
+//
+// Upgraded data is converted to the struct by way of JSON, so versioned types
+// must have JSON tags matching their Firestore tags.
+func decode[T any](doc *firestore.DocumentSnapshot, item *T) error {
+	collection := doc.Ref.Parent.ID
+	steps := migrations[collection]
+
+	version := documentVersion(doc)
+	if version >= len(steps) {
+		return doc.DataTo(item)
+	}
+
+	data := doc.Data()
+	for v := version; v < len(steps); v++ {
+		if err := steps[v](data); err != nil {
+			return fmt.Errorf("migrate from version %d: %w", v, err)
+		}
+	}
+	data["schemaVersion"] = len(steps)
+
+	b, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("encode migrated data: %w", err)
+	}
+	if err := json.Unmarshal(b, item); err != nil {
+		return fmt.Errorf("decode migrated data: %w", err)
+	}
+	return nil
+}
+
+// A DecodeFailure is a document that could not be converted to its type, even
+// after running migrations. These documents are invisible to the rest of the
+// bot, so they need a human to look at them.
+type DecodeFailure struct {
+	Path  string
+	Error string
+}
+
+// A MigrationReport summarizes a pass over all versioned documents.
+type MigrationReport struct {
+	// Scanned counts the documents in each collection.
+	Scanned map[string]int
+	// Outdated counts the documents in each collection that were written
+	// with an older schema (and were upgraded, unless this was a dry run).
+	Outdated map[string]int
+	// Failures lists every document that could not be decoded.
+	Failures []DecodeFailure
+}
+
+// MigrationsClient upgrades stored documents to the current schema versions.
+type MigrationsClient struct {
+	client *firestore.Client
+}
+
+func Migrations(client *firestore.Client) *MigrationsClient {
+	return &MigrationsClient{client}
+}
+
+// Run scans every versioned collection, rewriting outdated documents with
+// their upgraded data. With dryRun set, nothing is written. Either way, the
+// report lists the documents that couldn't be decoded.
+func (m *MigrationsClient) Run(ctx context.Context, dryRun bool) (MigrationReport, error) {
+	report := MigrationReport{
+		Scanned:  make(map[string]int),
+		Outdated: make(map[string]int),
+	}
+
+	for _, collection := range []string{"recursers", "reviews", "pairings"} {
+		var err error
+		switch collection {
+		case "recursers":
+			err = migrateCollection[Recurser](ctx, m.client, collection, dryRun, &report)
+		case "reviews":
+			err = migrateCollection[Review](ctx, m.client, collection, dryRun, &report)
+		case "pairings":
+			err = migrateCollection[Pairing](ctx, m.client, collection, dryRun, &report)
+		}
+		if err != nil {
+			return report, fmt.Errorf("migrate %s: %w", collection, err)
+		}
+	}
+
+	return report, nil
+}
+
+func migrateCollection[T any](ctx context.Context, client *firestore.Client, collection string, dryRun bool, report *MigrationReport) error {
+	current := CurrentSchemaVersion(collection)
+
+	iter := client.Collection(collection).Documents(ctx)
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		report.Scanned[collection]++
+
+		var item T
+		if err := decode(doc, &item); err != nil {
+			report.Failures = append(report.Failures, DecodeFailure{Path: doc.Ref.Path, Error: err.Error()})
+			continue
+		}
+
+		if documentVersion(doc) >= current {
+			continue
+		}
+
+		report.Outdated[collection]++
+		if dryRun {
+			continue
+		}
+
+		// The decoded struct carries the current version, so writing it back
+		// completes the upgrade. Skip documents that changed since we read
+		// them: whatever wrote them already wrote the current version.
+		err = client.RunTransaction(ctx, func(ctx context.Context, tx *firestore.Transaction) error {
+			latest, err := tx.Get(doc.Ref)
+			if err != nil {
+				return err
+			}
+			if !latest.UpdateTime.Equal(doc.UpdateTime) {
+				return nil
+			}
+			return tx.Set(doc.Ref, item)
+		})
+		if err != nil {
+			log.Printf("Could not write upgraded document %q: %s", doc.Ref.Path, err)
+			report.Failures = append(report.Failures, DecodeFailure{Path: doc.Ref.Path, Error: err.Error()})
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package store_test
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/internal/pbtest"
+	"github.com/recursecenter/pairing-bot/store"
+)
+
+func TestFirestoreMigrationsClient(t *testing.T) {
+	ctx := context.Background()
+
+	client := pbtest.FirestoreClient(t, ctx)
+	recursers := store.Recursers(client)
+
+	// Write documents the way the bot did before schema versioning.
+	oldID := pbtest.RandInt64(t)
+	oldDoc := client.Collection("recursers").Doc(strconv.FormatInt(oldID, 10))
+	_, err := oldDoc.Set(ctx, map[string]any{
+		"id":                 oldID,
+		"name":               "Old Name",
+		"email":              "old@recurse.example.net",
+		"isSkippingTomorrow": false,
+		"schedule":           store.DefaultSchedule(),
+		"currentlyAtRC":      true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	badDoc := client.Collection("recursers").Doc(strconv.FormatInt(pbtest.RandInt64(t), 10))
+	_, err = badDoc.Set(ctx, map[string]any{"schedule": "every day"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("upgrade on read", func(t *testing.T) {
+		actual, err := recursers.Get(ctx, oldID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := &store.Recurser{
+			ID:            oldID,
+			Name:          "Old Name",
+			Email:         "old@recurse.example.net",
+			Schedule:      store.DefaultSchedule(),
+			CurrentlyAtRC: true,
+			Partners:      store.PartnersAnyone,
+			SchemaVersion: store.CurrentSchemaVersion("recursers"),
+			IsSubscribed:  true,
+		}
+		assert.Equal(t, actual, expected)
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		report, err := store.Migrations(client).Run(ctx, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if report.Outdated["recursers"] == 0 {
+			t.Errorf("expected outdated recursers, got report %+v", report)
+		}
+		assertReportedFailure(t, report, badDoc.Path)
+
+		// Nothing was written.
+		doc, err := oldDoc.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := doc.DataAt("schemaVersion"); err == nil {
+			t.Errorf("dry run upgraded %q", oldDoc.Path)
+		}
+	})
+
+	t.Run("upgrade in batch", func(t *testing.T) {
+		report, err := store.Migrations(client).Run(ctx, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertReportedFailure(t, report, badDoc.Path)
+
+		doc, err := oldDoc.Get(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		version, err := doc.DataAt("schemaVersion")
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, version, any(int64(store.CurrentSchemaVersion("recursers"))))
+	})
+}
+
+func assertReportedFailure(t *testing.T, report store.MigrationReport, path string) {
+	t.Helper()
+	found := slices.ContainsFunc(report.Failures, func(f store.DecodeFailure) bool {
+		return f.Path == path
+	})
+	if !found {
+		t.Errorf("expected %q in failures, got %+v", path, report.Failures)
+	}
+}
@@ -31,3 +31,11 @@ func (s *SecretsClient) Get(ctx context.Context, name string) (string, error) {
 
 	return token.Value, nil
 }
+
+// Set creates or replaces the named secret.
+func (s *SecretsClient) Set(ctx context.Context, name string, value string) error {
+	_, err := s.client.Collection("secrets").Doc(name).Set(ctx, map[string]any{
+		"value": value,
+	})
+	return err
+}
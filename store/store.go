@@ -7,8 +7,9 @@ import (
 	"google.golang.org/api/iterator"
 )
 
-// fetchAll converts all documents in iter to values of type T. Documents that
-// cannot be converted will be skipped.
+// fetchAll converts all documents in iter to values of type T, upgrading any
+// that were written with an older schema version. Documents that cannot be
+// converted will be skipped; MigrationsClient.Run reports them.
 //
 // If the iterator yields an error instead of a document, this returns the
 // first such error and stops.
@@ -25,7 +26,7 @@ func fetchAll[T any](iter *firestore.DocumentIterator) ([]T, error) {
 		}
 
 		var item T
-		if err := doc.DataTo(&item); err != nil {
+		if err := decode(doc, &item); err != nil {
 			log.Printf("Skipping %q: %s", doc.Ref.Path, err)
 			continue
 		}
@@ -33,3 +34,32 @@ func fetchAll[T any](iter *firestore.DocumentIterator) ([]T, error) {
 		all = append(all, item)
 	}
 }
+
+// A document is a value converted from Firestore along with its document ID.
+type document[T any] struct {
+	ID   string
+	Data T
+}
+
+// fetchDocs is like fetchAll, but it keeps each value's document ID.
+func fetchDocs[T any](iter *firestore.DocumentIterator) ([]document[T], error) {
+	var all []document[T]
+	defer iter.Stop()
+
+	for {
+		doc, err := iter.Next()
+		if err == iterator.Done {
+			return all, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		var item T
+		if err := decode(doc, &item); err != nil {
+			log.Printf("Skipping %q: %s", doc.Ref.Path, err)
+			continue
+		}
+
+		all = append(all, document[T]{ID: doc.Ref.ID, Data: item})
+	}
+}
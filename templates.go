@@ -26,11 +26,29 @@ func renderWelcome(now time.Time) (string, error) {
 	})
 }
 
-func renderCheckin(now time.Time, numPairings int, numRecursers int, review string) (string, error) {
-	return renderTemplate("checkin.md.tmpl", map[string]any{
-		"Now":       now,
-		"Recursers": numRecursers,
-		"Pairings":  numPairings,
-		"Review":    review,
+func renderMiniWelcome(batch string) (string, error) {
+	return renderTemplate("miniWelcome.md.tmpl", map[string]any{
+		"Batch": batch,
 	})
 }
+
+// checkinStats are the numbers reported in the weekly checkin.
+type checkinStats struct {
+	Now time.Time
+
+	// Recursers is the number of subscribers, of which Alumni have left RC.
+	Recursers int
+	Alumni    int
+
+	// Pairings is the number of pairs made in the last week, of which
+	// AlumniPairings included at least one alum.
+	Pairings       int
+	AlumniPairings int
+
+	// Review is a random review, or empty if there aren't any.
+	Review string
+}
+
+func renderCheckin(stats checkinStats) (string, error) {
+	return renderTemplate("checkin.md.tmpl", stats)
+}
@@ -3,6 +3,7 @@ package zulip
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
@@ -11,6 +12,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 )
 
 var defaultBaseURL *url.URL = must(url.Parse("https://recurse.zulipchat.com/api/v1/"))
@@ -29,23 +31,35 @@ type Client struct {
 	http        *http.Client
 	baseURL     *url.URL
 	credentials CredentialsFunc
+
+	maxAttempts int
+	minBackoff  time.Duration
+	maxBackoff  time.Duration
+
+	// rateLimit tracks the server's most recent rate-limit headers so that
+	// requests can wait out an exhausted budget instead of being rejected.
+	rateLimit rateLimiter
 }
 
 // NewClient creates a new Zulip API client.
 func NewClient(credentials CredentialsFunc, opts ...ClientOpt) (*Client, error) {
-	client := Client{
+	client := &Client{
 		http:        http.DefaultClient,
 		baseURL:     defaultBaseURL,
 		credentials: credentials,
+
+		maxAttempts: defaultMaxAttempts,
+		minBackoff:  defaultMinBackoff,
+		maxBackoff:  defaultMaxBackoff,
 	}
 
 	for i, opt := range opts {
-		if err := opt(&client); err != nil {
+		if err := opt(client); err != nil {
 			return nil, fmt.Errorf("zulip client option %d: %w", i, err)
 		}
 	}
 
-	return &client, nil
+	return client, nil
 }
 
 // PostToTopic sends a chat message to the given stream and topic.
@@ -109,33 +123,127 @@ func (c *Client) postForm(ctx context.Context, endpoint *url.URL, form url.Value
 
 	req.Header.Set("content-type", "application/x-www-form-urlencoded")
 
+	status, body, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("zulip response: %d %s\n", status, string(body))
+	return nil
+}
+
+// get sends the GET request with authorization and encoded query params, then
+// decodes the JSON response body into v. This returns a non-nil error if the
+// response status code indicates an error (400 or higher) or if the request
+// could not be sent.
+func (c *Client) get(ctx context.Context, endpoint *url.URL, params url.Values, v any) error {
+	u := *endpoint
+	u.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodGet,
+		u.String(),
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("build request: %w", err)
+	}
+
+	_, body, err := c.do(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("decode response body: %w", err)
+	}
+	return nil
+}
+
+// do authorizes and sends the request and returns the response status code
+// and body.
+//
+// Failures that are safe to repeat (see isRetryable) are retried up to the
+// client's maximum number of attempts, waiting between each one according to
+// the server's rate-limit headers or an exponential backoff. The server can't
+// make the client wait longer than its maximum backoff: asking for more fails
+// the request with ErrRateLimited.
+func (c *Client) do(ctx context.Context, req *http.Request) (int, []byte, error) {
 	creds, err := c.credentials(ctx)
 	if err != nil {
-		return fmt.Errorf("fetch credentials: %w", err)
+		return 0, nil, fmt.Errorf("fetch credentials: %w", err)
 	}
 	req.SetBasicAuth(creds.Username, creds.Password)
 
+	for attempt := 1; ; attempt++ {
+		if err := c.rateLimit.wait(ctx, c.maxBackoff); err != nil {
+			return 0, nil, err
+		}
+
+		status, body, err := c.send(req)
+		if err == nil {
+			return status, body, nil
+		}
+
+		if attempt >= c.maxAttempts || !isRetryable(req, err) {
+			return 0, nil, err
+		}
+
+		delay := c.backoff(attempt)
+		if retryAfter := retryAfter(err); retryAfter > c.maxBackoff {
+			return 0, nil, fmt.Errorf("%w: asked to retry in %s: %w", ErrRateLimited, retryAfter.Round(time.Second), err)
+		} else if retryAfter > delay {
+			delay = retryAfter
+		}
+
+		log.Printf("Retrying %s %s (attempt %d of %d) in %s: %s", req.Method, req.URL.Path, attempt+1, c.maxAttempts, delay, err)
+
+		if err := sleep(ctx, delay); err != nil {
+			return 0, nil, err
+		}
+
+		// The previous attempt consumed the request body, so get a fresh copy.
+		if req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return 0, nil, fmt.Errorf("rewind request body: %w", err)
+			}
+		}
+	}
+}
+
+// send makes a single attempt at the request and returns the response status
+// code and body.
+func (c *Client) send(req *http.Request) (int, []byte, error) {
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return err
+		return 0, nil, err
 	}
 	defer resp.Body.Close()
 
+	c.rateLimit.update(resp.Header)
+
 	// This read will consume the body...
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("read response body: %w", err)
+		return 0, nil, fmt.Errorf("read response body: %w", err)
 	}
 
 	// ... so replace the content afterward.
 	resp.Body = io.NopCloser(bytes.NewReader(body))
 
-	log.Printf("zulip response: %d %s\n", resp.StatusCode, string(body))
-
 	if resp.StatusCode >= 400 {
-		return &ResponseError{resp}
+		log.Printf("zulip response: %d %s\n", resp.StatusCode, string(body))
+
+		// Not every error response has a JSON body, so leave the code empty
+		// if there isn't one.
+		var result struct {
+			Code string `json:"code"`
+		}
+		_ = json.Unmarshal(body, &result)
+		return 0, nil, &ResponseError{Response: resp, Code: result.Code}
 	}
-	return nil
+	return resp.StatusCode, body, nil
 }
 
 // A ClientOpt is used to configure a Client.
@@ -174,10 +282,47 @@ func WithBaseURL(baseURL string) ClientOpt {
 	}
 }
 
+// WithMaxAttempts sets the number of times a request may be sent before giving
+// up. A value of 1 disables retries.
+//
+// The default value is 3.
+func WithMaxAttempts(n int) ClientOpt {
+	return func(c *Client) error {
+		if n < 1 {
+			return fmt.Errorf("max attempts must be at least 1, got %d", n)
+		}
+
+		c.maxAttempts = n
+		return nil
+	}
+}
+
+// WithBackoff sets the range of delays between retried requests. The delay
+// before each retry doubles from min up to max, with random jitter. max is
+// also the longest the server's rate-limit headers can make the client wait.
+//
+// The default values are 500ms and 30s.
+func WithBackoff(min, max time.Duration) ClientOpt {
+	return func(c *Client) error {
+		if min <= 0 || max < min {
+			return fmt.Errorf("invalid backoff range [%s, %s]", min, max)
+		}
+
+		c.minBackoff = min
+		c.maxBackoff = max
+		return nil
+	}
+}
+
 // ResponseError is the type of error returned when the response status
 // indicates an error (400 or greater).
 type ResponseError struct {
 	Response *http.Response
+	// Code is Zulip's error code from the response body, like "BAD_REQUEST",
+	// or empty if the body didn't have one.
+	//
+	// https://zulip.com/api/rest-error-handling
+	Code string
 }
 
 func (r *ResponseError) Error() string {
@@ -2,12 +2,15 @@ package zulip_test
 
 import (
 	"context"
+	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/recursecenter/pairing-bot/internal/assert"
 	"github.com/recursecenter/pairing-bot/zulip"
@@ -196,3 +199,191 @@ func TestClient_zulip_errors(t *testing.T) {
 
 	srv.AssertRequestCount(1)
 }
+
+// retryClient creates a client for the mock server that retries quickly.
+func retryClient(t *testing.T, srv *MockServer, opts ...zulip.ClientOpt) *zulip.Client {
+	t.Helper()
+
+	opts = append([]zulip.ClientOpt{
+		zulip.WithHTTP(srv.Client()),
+		zulip.WithBaseURL(srv.URL()),
+		zulip.WithBackoff(time.Millisecond, time.Millisecond),
+	}, opts...)
+
+	client, err := zulip.NewClient(zulip.StaticCredentials("fake-username", "fake-password"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client
+}
+
+func TestClient_retries(t *testing.T) {
+	t.Run("retry rate-limited message", func(t *testing.T) {
+		var count atomic.Int64
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			// The body must be replayed in full on each attempt.
+			if assert.NoError(t, r.ParseForm()) {
+				assert.Equal(t, r.Form.Get("content"), "Okay, go!")
+			}
+
+			if count.Add(1) == 1 {
+				w.Header().Set("Retry-After", "0.001")
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		})
+
+		client := retryClient(t, srv)
+
+		err := client.SendUserMessage(context.Background(), []int64{0, 1}, "Okay, go!")
+		assert.NoError(t, err)
+
+		srv.AssertRequestCount(2)
+	})
+
+	t.Run("retry unavailable message", func(t *testing.T) {
+		var count atomic.Int64
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if count.Add(1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		})
+
+		client := retryClient(t, srv)
+
+		err := client.SendUserMessage(context.Background(), []int64{0, 1}, "Okay, go!")
+		assert.NoError(t, err)
+
+		srv.AssertRequestCount(3)
+	})
+
+	t.Run("do not retry message after server error", func(t *testing.T) {
+		// The message may have been sent already, so retrying could send a
+		// duplicate.
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		})
+
+		client := retryClient(t, srv)
+
+		err := client.SendUserMessage(context.Background(), []int64{0, 1}, "Okay, go!")
+		if respErr, ok := assert.ErrorAs[*zulip.ResponseError](t, err); ok {
+			assert.Equal(t, respErr.Response.StatusCode, 500)
+		}
+
+		srv.AssertRequestCount(1)
+	})
+
+	t.Run("retry lookup after server error", func(t *testing.T) {
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		})
+
+		client := retryClient(t, srv, zulip.WithMaxAttempts(4))
+
+		_, err := client.GetUser(context.Background(), 1000)
+		if respErr, ok := assert.ErrorAs[*zulip.ResponseError](t, err); ok {
+			assert.Equal(t, respErr.Response.StatusCode, 502)
+		}
+
+		srv.AssertRequestCount(4)
+	})
+
+	t.Run("retries disabled", func(t *testing.T) {
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		client := retryClient(t, srv, zulip.WithMaxAttempts(1))
+
+		err := client.SendUserMessage(context.Background(), []int64{0, 1}, "Okay, go!")
+		if respErr, ok := assert.ErrorAs[*zulip.ResponseError](t, err); ok {
+			assert.Equal(t, respErr.Response.StatusCode, 429)
+		}
+
+		srv.AssertRequestCount(1)
+	})
+
+	t.Run("wait for exhausted rate limit", func(t *testing.T) {
+		reset := time.Now().Add(50 * time.Millisecond).Truncate(time.Millisecond)
+
+		var count atomic.Int64
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if count.Add(1) == 1 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%.3f", float64(reset.UnixMilli())/1000))
+				return
+			}
+
+			if time.Now().Before(reset) {
+				t.Errorf("request sent %s before rate limit reset", time.Until(reset))
+			}
+		})
+
+		client := retryClient(t, srv, zulip.WithBackoff(time.Millisecond, time.Second))
+		ctx := context.Background()
+
+		assert.NoError(t, client.SendUserMessage(ctx, []int64{0, 1}, "one"))
+		assert.NoError(t, client.SendUserMessage(ctx, []int64{0, 1}, "two"))
+
+		srv.AssertRequestCount(2)
+	})
+
+	t.Run("do not wait too long to retry", func(t *testing.T) {
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "86400")
+			w.WriteHeader(http.StatusTooManyRequests)
+		})
+
+		client := retryClient(t, srv)
+
+		err := client.SendUserMessage(context.Background(), []int64{0, 1}, "Okay, go!")
+		assert.ErrorIs(t, err, zulip.ErrRateLimited)
+		if respErr, ok := assert.ErrorAs[*zulip.ResponseError](t, err); ok {
+			assert.Equal(t, respErr.Response.StatusCode, 429)
+		}
+
+		srv.AssertRequestCount(1)
+	})
+
+	t.Run("do not wait too long for rate limit reset", func(t *testing.T) {
+		var count atomic.Int64
+		srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+			if assert.NoError(t, r.ParseForm()) && r.Form.Get("content") == "two" {
+				t.Error("request sent while rate limited")
+			}
+
+			if count.Add(1) == 1 {
+				w.Header().Set("X-RateLimit-Remaining", "0")
+				w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(24*time.Hour).Unix(), 10))
+			}
+		})
+
+		client := retryClient(t, srv)
+		ctx := context.Background()
+
+		assert.NoError(t, client.SendUserMessage(ctx, []int64{0, 1}, "one"))
+
+		// The next request fails without being sent...
+		err := client.SendUserMessage(ctx, []int64{0, 1}, "two")
+		assert.ErrorIs(t, err, zulip.ErrRateLimited)
+
+		// ... but the one after that asks the server again.
+		assert.NoError(t, client.SendUserMessage(ctx, []int64{0, 1}, "three"))
+
+		srv.AssertRequestCount(2)
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		creds := zulip.StaticCredentials("fake-username", "fake-password")
+
+		_, err := zulip.NewClient(creds, zulip.WithMaxAttempts(0))
+		if err == nil {
+			t.Error("expected error for zero max attempts")
+		}
+
+		_, err = zulip.NewClient(creds, zulip.WithBackoff(time.Second, time.Millisecond))
+		if err == nil {
+			t.Error("expected error for inverted backoff range")
+		}
+	})
+}
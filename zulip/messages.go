@@ -0,0 +1,91 @@
+package zulip
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+// messagesPageSize is the number of messages requested per page of history.
+// Zulip allows up to 5000, but smaller pages keep each response manageable.
+const messagesPageSize = 1000
+
+// A NarrowTerm is one filter in a message history search.
+//
+// https://zulip.com/api/construct-narrow
+type NarrowTerm struct {
+	Operator string `json:"operator"`
+	Operand  any    `json:"operand"`
+}
+
+// DirectMessagesWith returns a narrow matching the (group) direct message
+// conversation between exactly this set of users.
+func DirectMessagesWith(userIDs []int64) []NarrowTerm {
+	return []NarrowTerm{{Operator: "dm", Operand: userIDs}}
+}
+
+// GetMessages fetches every message matching the narrow, oldest first.
+//
+// https://zulip.com/api/get-messages
+func (c *Client) GetMessages(ctx context.Context, narrow []NarrowTerm) ([]Message, error) {
+	var messages []Message
+	anchor := "oldest"
+	var lastID int64
+
+	for {
+		page, err := c.getMessages(ctx, narrow, anchor)
+		if err != nil {
+			return nil, fmt.Errorf("get messages (anchor=%s): %w", anchor, err)
+		}
+
+		// The anchor message itself is included in each page, so skip
+		// anything we've already seen.
+		var added int
+		for _, m := range page.Messages {
+			if m.ID > lastID {
+				messages = append(messages, m)
+				lastID = m.ID
+				added++
+			}
+		}
+
+		// Zulip tells us when we've reached the most recent message. A page
+		// with nothing new also means there's nothing left, even if it didn't
+		// say so.
+		if page.FoundNewest || added == 0 {
+			return messages, nil
+		}
+
+		anchor = strconv.FormatInt(lastID, 10)
+	}
+}
+
+// messagesPage is one page of results from the message history endpoint.
+type messagesPage struct {
+	Messages    []Message `json:"messages"`
+	FoundNewest bool      `json:"found_newest"`
+}
+
+// getMessages loads one page of messages starting at the anchor.
+//
+// https://zulip.com/api/get-messages
+func (c *Client) getMessages(ctx context.Context, narrow []NarrowTerm, anchor string) (messagesPage, error) {
+	narrowJSON, err := json.Marshal(narrow)
+	if err != nil {
+		return messagesPage{}, fmt.Errorf("encode narrow: %w", err)
+	}
+
+	params := make(url.Values)
+	params.Set("anchor", anchor)
+	params.Set("num_before", "0")
+	params.Set("num_after", strconv.Itoa(messagesPageSize))
+	params.Set("narrow", string(narrowJSON))
+	params.Set("apply_markdown", "false")
+
+	endpoint := c.baseURL.JoinPath("messages")
+
+	var page messagesPage
+	return page, c.get(ctx, endpoint, params, &page)
+}
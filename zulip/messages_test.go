@@ -0,0 +1,90 @@
+package zulip_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/zulip"
+)
+
+func fakeMessages(n int) []zulip.Message {
+	var all []zulip.Message
+	for i := range n {
+		all = append(all, zulip.Message{
+			ID:       int64(100 + i),
+			SenderID: int64(i % 2),
+			Content:  "message " + strconv.Itoa(i),
+		})
+	}
+	return all
+}
+
+func TestClient_GetMessages(t *testing.T) {
+	// Two full pages and a partial one: 1000 + 999 + 501 new messages, since
+	// each page after the first repeats its anchor message.
+	allMessages := fakeMessages(2500)
+
+	type PageParams struct {
+		Anchor string
+		Start  int
+		End    int
+		Newest bool
+	}
+
+	expectedPages := []PageParams{
+		{"oldest", 0, 1000, false},
+		{"1099", 999, 1999, false},
+		{"2098", 1998, 2500, true},
+	}
+
+	pageIdx := 0
+	srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodGet)
+		assert.Equal(t, r.URL.Path, "/messages")
+
+		page := expectedPages[pageIdx]
+		pageIdx++
+
+		params := url.Values{
+			"anchor":         []string{page.Anchor},
+			"num_before":     []string{"0"},
+			"num_after":      []string{"1000"},
+			"narrow":         []string{`[{"operator":"dm","operand":[0,1]}]`},
+			"apply_markdown": []string{"false"},
+		}
+		assert.Equal(t, r.URL.Query(), params)
+
+		err := json.NewEncoder(w).Encode(map[string]any{
+			"result":       "success",
+			"messages":     allMessages[page.Start:page.End],
+			"found_newest": page.Newest,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	defer srv.AssertRequestCount(len(expectedPages))
+
+	client, err := zulip.NewClient(
+		zulip.StaticCredentials("fake-username", "fake-password"),
+		zulip.WithHTTP(srv.Client()),
+		zulip.WithBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	messages, err := client.GetMessages(ctx, zulip.DirectMessagesWith([]int64{0, 1}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, messages, allMessages)
+}
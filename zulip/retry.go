@@ -0,0 +1,158 @@
+package zulip
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultMinBackoff  = 500 * time.Millisecond
+	defaultMaxBackoff  = 30 * time.Second
+)
+
+// ErrRateLimited is returned instead of waiting when the server asks the
+// client to hold off for longer than its maximum backoff. A bad header could
+// otherwise block a job indefinitely.
+var ErrRateLimited = errors.New("rate limited for too long")
+
+// isRetryable reports whether a failed request can be sent again without
+// risking duplicate side effects, like a message being posted twice.
+//
+// GET requests are read-only, so any transient failure is fair game. Other
+// requests (sending messages) are only retried when we know the server never
+// acted on them: the connection couldn't be made at all, the request was
+// rejected for exceeding the rate limit, or the server was unavailable.
+func isRetryable(req *http.Request, err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var respErr *ResponseError
+	if errors.As(err, &respErr) {
+		switch code := respErr.Response.StatusCode; {
+		case code == http.StatusTooManyRequests, code == http.StatusServiceUnavailable:
+			return true
+		case code >= 500:
+			return req.Method == http.MethodGet
+		default:
+			return false
+		}
+	}
+
+	// Anything else is a transport error. Dial errors mean nothing was sent.
+	var opErr *net.OpError
+	if errors.As(err, &opErr) && opErr.Op == "dial" {
+		return true
+	}
+	return req.Method == http.MethodGet
+}
+
+// backoff returns the delay before the next attempt: an exponentially
+// increasing cap with "full jitter" to keep concurrent clients from retrying
+// in lockstep.
+//
+// https://aws.amazon.com/blogs/architecture/exponential-backoff-and-jitter/
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.minBackoff
+	for i := 1; i < attempt && ceiling < c.maxBackoff; i++ {
+		ceiling *= 2
+	}
+	ceiling = min(ceiling, c.maxBackoff)
+
+	return c.minBackoff + time.Duration(rand.Int63n(int64(ceiling-c.minBackoff)+1))
+}
+
+// retryAfter returns the delay requested by the server's Retry-After header,
+// or zero if there isn't one.
+//
+// https://zulip.com/api/http-headers#rate-limiting-response-headers
+func retryAfter(err error) time.Duration {
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		return 0
+	}
+
+	value := respErr.Response.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+
+	// Zulip sends (possibly fractional) seconds, but the header may also be
+	// an HTTP date.
+	if secs, err := strconv.ParseFloat(value, 64); err == nil {
+		return time.Duration(secs * float64(time.Second))
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// rateLimiter holds requests back while the server says the client's request
+// budget is exhausted.
+//
+// https://zulip.com/api/http-headers#rate-limiting-response-headers
+type rateLimiter struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+// update records the rate-limit state from the response headers.
+func (r *rateLimiter) update(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil || remaining > 0 {
+		return
+	}
+
+	reset, err := strconv.ParseFloat(header.Get("X-RateLimit-Reset"), 64)
+	if err != nil {
+		return
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.until = time.Unix(0, int64(reset*float64(time.Second)))
+}
+
+// wait blocks until the rate limit has reset or the context is done. If the
+// reset is more than max away, it returns ErrRateLimited instead, and forgets
+// the reset so that the next request asks the server again.
+func (r *rateLimiter) wait(ctx context.Context, max time.Duration) error {
+	r.mu.Lock()
+	delay := time.Until(r.until)
+	if delay > max {
+		r.until = time.Time{}
+	}
+	r.mu.Unlock()
+
+	if delay > max {
+		return fmt.Errorf("%w: rate limit resets in %s", ErrRateLimited, delay.Round(time.Second))
+	}
+	return sleep(ctx, delay)
+}
+
+// sleep pauses for the duration or until the context is done, whichever comes
+// first.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,83 @@
+package zulip
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// An Account contains the details of a Zulip user's account.
+//
+// https://zulip.com/api/get-user#response
+type Account struct {
+	UserID   int64  `json:"user_id"`
+	Email    string `json:"email"`
+	FullName string `json:"full_name"`
+	IsActive bool   `json:"is_active"`
+	IsBot    bool   `json:"is_bot"`
+	TimeZone string `json:"timezone"`
+}
+
+// GetUser fetches the account details for a Zulip user ID.
+//
+// https://zulip.com/api/get-user
+func (c *Client) GetUser(ctx context.Context, userID int64) (Account, error) {
+	return c.getUser(ctx, strconv.FormatInt(userID, 10))
+}
+
+// GetUserByEmail fetches the account details for a Zulip user's email address.
+//
+// https://zulip.com/api/get-user-by-email
+func (c *Client) GetUserByEmail(ctx context.Context, email string) (Account, error) {
+	return c.getUser(ctx, email)
+}
+
+// getUser fetches an account by either of the identifiers that the users
+// endpoint accepts: the numeric user ID or the email address.
+func (c *Client) getUser(ctx context.Context, key string) (Account, error) {
+	endpoint := c.baseURL.JoinPath("users", key)
+
+	var body struct {
+		User Account `json:"user"`
+	}
+	if err := c.get(ctx, endpoint, nil, &body); err != nil {
+		return Account{}, err
+	}
+	return body.User, nil
+}
+
+// IsNoSuchUser reports whether err is Zulip's response to a request for a
+// user that doesn't exist. Other errors, like an invalid API key, don't say
+// anything about the user.
+func IsNoSuchUser(err error) bool {
+	var respErr *ResponseError
+	if !errors.As(err, &respErr) {
+		return false
+	}
+
+	switch respErr.Response.StatusCode {
+	case http.StatusNotFound:
+		return true
+	case http.StatusBadRequest:
+		return respErr.Code == "BAD_REQUEST"
+	default:
+		return false
+	}
+}
+
+// ListUsers fetches the account details for every user in the organization,
+// including deactivated users and bots.
+//
+// https://zulip.com/api/get-users
+func (c *Client) ListUsers(ctx context.Context) ([]Account, error) {
+	endpoint := c.baseURL.JoinPath("users")
+
+	var body struct {
+		Members []Account `json:"members"`
+	}
+	if err := c.get(ctx, endpoint, nil, &body); err != nil {
+		return nil, err
+	}
+	return body.Members, nil
+}
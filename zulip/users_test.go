@@ -0,0 +1,141 @@
+package zulip_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/recursecenter/pairing-bot/internal/assert"
+	"github.com/recursecenter/pairing-bot/zulip"
+)
+
+func TestClient_GetUser(t *testing.T) {
+	account := zulip.Account{
+		UserID:   1000,
+		Email:    "fake-1000@recurse.example.net",
+		FullName: "Your Name",
+		IsActive: true,
+		IsBot:    false,
+		TimeZone: "America/New_York",
+	}
+
+	srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodGet)
+
+		// Both lookups hit the same endpoint with a different path key.
+		switch r.URL.Path {
+		case "/users/1000", "/users/fake-1000@recurse.example.net":
+		default:
+			t.Errorf("unexpected path: %q", r.URL.Path)
+		}
+
+		// Base64-encoding of "fake-username:fake-password"
+		authz := "Basic ZmFrZS11c2VybmFtZTpmYWtlLXBhc3N3b3Jk"
+		assert.Equal(t, r.Header.Get("Authorization"), authz)
+
+		err := json.NewEncoder(w).Encode(map[string]any{
+			"result": "success",
+			"msg":    "",
+			"user":   account,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := zulip.NewClient(
+		zulip.StaticCredentials("fake-username", "fake-password"),
+		zulip.WithHTTP(srv.Client()),
+		zulip.WithBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	byID, err := client.GetUser(ctx, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, byID, account)
+
+	byEmail, err := client.GetUserByEmail(ctx, "fake-1000@recurse.example.net")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, byEmail, account)
+
+	srv.AssertRequestCount(2)
+}
+
+func TestClient_ListUsers(t *testing.T) {
+	accounts := []zulip.Account{
+		{UserID: 1, Email: "one@recurse.example.net", FullName: "One", IsActive: true},
+		{UserID: 2, Email: "two@recurse.example.net", FullName: "Two", IsActive: false},
+		{UserID: 3, Email: "bot@recurse.example.net", FullName: "Bot", IsActive: true, IsBot: true},
+	}
+
+	srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.Method, http.MethodGet)
+		assert.Equal(t, r.URL.Path, "/users")
+
+		err := json.NewEncoder(w).Encode(map[string]any{
+			"result":  "success",
+			"msg":     "",
+			"members": accounts,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	client, err := zulip.NewClient(
+		zulip.StaticCredentials("fake-username", "fake-password"),
+		zulip.WithHTTP(srv.Client()),
+		zulip.WithBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	actual, err := client.ListUsers(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, actual, accounts)
+
+	srv.AssertRequestCount(1)
+}
+
+func TestClient_GetUser_errors(t *testing.T) {
+	srv := mockServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		if _, err := w.Write([]byte(`{"result":"error","msg":"No such user","code":"BAD_REQUEST"}`)); err != nil {
+			panic(err)
+		}
+	})
+
+	client, err := zulip.NewClient(
+		zulip.StaticCredentials("fake-username", "fake-password"),
+		zulip.WithHTTP(srv.Client()),
+		zulip.WithBaseURL(srv.URL()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+
+	_, err = client.GetUser(ctx, 404)
+	if respErr, ok := assert.ErrorAs[*zulip.ResponseError](t, err); ok {
+		assert.Equal(t, respErr.Response.StatusCode, 400)
+		assert.Equal(t, respErr.Code, "BAD_REQUEST")
+	}
+	assert.Equal(t, zulip.IsNoSuchUser(err), true)
+
+	srv.AssertRequestCount(1)
+}
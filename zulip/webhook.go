@@ -19,14 +19,20 @@ type Webhook struct {
 	Message Message `json:"message"`
 }
 
-// Message contains the details of the chat message that triggered the webhook.
+// Message contains the details of a chat message, either the one that
+// triggered the webhook or one fetched from the message history.
 //
 // https://zulip.com/api/outgoing-webhooks#fields-documentation
+// https://zulip.com/api/get-messages#response
 type Message struct {
+	ID               int64            `json:"id"`
 	DisplayRecipient DisplayRecipient `json:"display_recipient"`
 	SenderID         int64            `json:"sender_id"`
 	SenderEmail      string           `json:"sender_email"`
 	SenderFullName   string           `json:"sender_full_name"`
+	Subject          string           `json:"subject"`
+	Content          string           `json:"content"`
+	Timestamp        int64            `json:"timestamp"`
 }
 
 // DisplayRecipient represents the recipient of the message, either a stream
@@ -61,6 +67,13 @@ func (d *DisplayRecipient) UnmarshalJSON(b []byte) error {
 	return errors.New("invalid value for DisplayRecipient")
 }
 
+func (d DisplayRecipient) MarshalJSON() ([]byte, error) {
+	if d.Users != nil {
+		return json.Marshal(d.Users)
+	}
+	return json.Marshal(d.Stream)
+}
+
 type User struct {
 	ID int64 `json:"id"`
 }
@@ -37,6 +37,7 @@ func TestWebhook(t *testing.T) {
 				SenderID:         1000,
 				SenderEmail:      "fake-1000@recurse.example.net",
 				SenderFullName:   "Your Name",
+				Subject:          "Subject Topic",
 			},
 		},
 	}